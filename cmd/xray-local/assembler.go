@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/internal/xrayserver"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// assembler collects the segment documents, and assembles them into trace trees.
+type assembler struct {
+	// the duration to wait for the subsegments that are completed after the root segment.
+	wait time.Duration
+
+	// the duration to give up waiting for the root segment.
+	timeout time.Duration
+
+	mu     sync.Mutex
+	traces map[string]*pendingTrace
+}
+
+type pendingTrace struct {
+	docs []*schema.Segment
+
+	// the time when the first document is received.
+	firstSeen time.Time
+
+	// the time when the root segment is completed.
+	completed time.Time
+}
+
+func newAssembler(wait, timeout time.Duration) *assembler {
+	return &assembler{
+		wait:    wait,
+		timeout: timeout,
+		traces:  make(map[string]*pendingTrace),
+	}
+}
+
+// add adds the segment document.
+func (a *assembler) add(now time.Time, doc *schema.Segment) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.traces[doc.TraceID]
+	if !ok {
+		t = &pendingTrace{firstSeen: now}
+		a.traces[doc.TraceID] = t
+	}
+	t.docs = append(t.docs, doc)
+
+	// the SDK injects the service information only into the root segments.
+	if doc.Service != nil && !doc.InProgress && t.completed.IsZero() {
+		t.completed = now
+	}
+}
+
+// flush returns the traces that are ready to print, in order of their first documents.
+// The traces whose root segment is not received are returned after the timeout.
+func (a *assembler) flush(now time.Time, force bool) []*schema.Segment {
+	a.mu.Lock()
+	var ready []*pendingTrace
+	for id, t := range a.traces {
+		if force ||
+			(!t.completed.IsZero() && now.Sub(t.completed) >= a.wait) ||
+			now.Sub(t.firstSeen) >= a.timeout {
+			ready = append(ready, t)
+			delete(a.traces, id)
+		}
+	}
+	a.mu.Unlock()
+
+	sort.Slice(ready, func(i, j int) bool {
+		return ready[i].firstSeen.Before(ready[j].firstSeen)
+	})
+	trees := make([]*schema.Segment, 0, len(ready))
+	for _, t := range ready {
+		tree, err := xrayserver.Tree(t.docs)
+		if err != nil {
+			// the root segment is missing. print the fragments as they are.
+			trees = append(trees, t.docs...)
+			continue
+		}
+		trees = append(trees, tree)
+	}
+	return trees
+}
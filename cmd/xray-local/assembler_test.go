@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func TestAssembler(t *testing.T) {
+	a := newAssembler(time.Second, 10*time.Second)
+	now := time.Unix(1000000000, 0)
+
+	a.add(now, &schema.Segment{
+		Name:     "sub",
+		ID:       "0000000000000002",
+		TraceID:  "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		ParentID: "0000000000000001",
+		Type:     "subsegment",
+	})
+	a.add(now, &schema.Segment{
+		Name:    "orphan",
+		ID:      "0000000000000004",
+		TraceID: "1-5e645f3e-2dfad076a177c5ccc5de12f5",
+	})
+	if trees := a.flush(now, false); len(trees) != 0 {
+		t.Errorf("want no trees, got %d", len(trees))
+	}
+
+	a.add(now.Add(time.Second), &schema.Segment{
+		Name:    "root",
+		ID:      "0000000000000001",
+		TraceID: "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		Service: &schema.Service{},
+	})
+	// waiting for the subsegments that are completed after the root segment.
+	if trees := a.flush(now.Add(time.Second), false); len(trees) != 0 {
+		t.Errorf("want no trees, got %d", len(trees))
+	}
+
+	trees := a.flush(now.Add(2*time.Second), false)
+	if len(trees) != 1 {
+		t.Fatalf("want 1 tree, got %d", len(trees))
+	}
+	if trees[0].Name != "root" || len(trees[0].Subsegments) != 1 {
+		t.Errorf("unexpected tree: %#v", trees[0])
+	}
+
+	// the root segment of the orphan is never received.
+	trees = a.flush(now.Add(10*time.Second), false)
+	if len(trees) != 1 {
+		t.Fatalf("want 1 tree, got %d", len(trees))
+	}
+	if trees[0].Name != "orphan" {
+		t.Errorf("unexpected tree: %#v", trees[0])
+	}
+}
@@ -0,0 +1,191 @@
+// Command xray-local is a replacement of AWS X-Ray daemon for local development.
+// It receives the segment documents on UDP, serves the sampling API on TCP,
+// and prints the assembled trace trees instead of sending them to AWS X-Ray.
+// With the -http flag, it also serves a web UI for browsing the recent traces.
+//
+//	xray-local -manifest sampling.json -format text -http 127.0.0.1:2001
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/internal/xrayserver"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xrayviewer"
+)
+
+type header struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+func main() {
+	var udpAddr, tcpAddr, httpAddr, manifestPath, format string
+	var wait, timeout time.Duration
+	var size int
+	flag.StringVar(&udpAddr, "udp", "127.0.0.1:2000", "the address for receiving segment documents")
+	flag.StringVar(&tcpAddr, "tcp", "127.0.0.1:2000", "the address for serving the sampling API")
+	flag.StringVar(&httpAddr, "http", "", "the address for serving the trace viewer. by default, the viewer is disabled")
+	flag.IntVar(&size, "size", xrayviewer.DefaultSize, "the number of traces kept by the trace viewer")
+	flag.StringVar(&manifestPath, "manifest", "", "the path to the local sampling rule manifest. by default, the default rule of AWS X-Ray is used")
+	flag.StringVar(&format, "format", "text", "the output format: text or json")
+	flag.DurationVar(&wait, "wait", time.Second, "the duration to wait for the subsegments that are completed after the root segment")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "the duration to give up waiting for the root segment")
+	flag.Parse()
+
+	var p printer
+	switch format {
+	case "text":
+		p = newTextPrinter(os.Stdout)
+	case "json":
+		p = newJSONPrinter(os.Stdout)
+	default:
+		log.Fatalf("unknown format: %s", format)
+	}
+
+	server := xrayserver.NewSamplingServer()
+	if manifestPath != "" {
+		manifest, err := readManifest(manifestPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.SetRules(xrayserver.SamplingRulesFromManifest(manifest)...)
+	}
+
+	conn, err := net.ListenPacket("udp", udpAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	l, err := net.Listen("tcp", tcpAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	httpServer := &http.Server{Handler: server}
+	go func() {
+		if err := httpServer.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	var viewer *xrayviewer.Viewer
+	if httpAddr != "" {
+		viewer = xrayviewer.New(size)
+		l, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("serving trace viewer on http://%s/", l.Addr())
+		go func() {
+			if err := http.Serve(l, viewer); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+		conn.Close()
+	}()
+
+	log.Printf("receiving segments on udp %s, serving sampling API on tcp %s", conn.LocalAddr(), l.Addr())
+	a := newAssembler(wait, timeout)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		receive(ctx, conn, a)
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			printAll(p, viewer, a.flush(now, false))
+		case <-done:
+			printAll(p, viewer, a.flush(time.Now(), true))
+			httpServer.Shutdown(context.Background())
+			return
+		}
+	}
+}
+
+func readManifest(path string) (*sampling.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return sampling.DecodeManifest(f)
+}
+
+func receive(ctx context.Context, conn net.PacketConn, a *assembler) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("failed to read: %v", err)
+			}
+			return
+		}
+		doc, err := decodePacket(buf[:n])
+		if err != nil {
+			log.Printf("failed to decode: %v", err)
+			continue
+		}
+		a.add(time.Now(), doc)
+	}
+}
+
+// decodePacket decodes a packet in the format of AWS X-Ray daemon.
+// The packet is a header line `{"format":"json","version":1}` followed by a segment document.
+func decodePacket(data []byte) (*schema.Segment, error) {
+	idx := bytes.IndexByte(data, '\n')
+	if idx < 0 {
+		return nil, errors.New("the header is not found")
+	}
+	var h header
+	if err := json.Unmarshal(data[:idx], &h); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if h.Format != "json" || h.Version != 1 {
+		return nil, fmt.Errorf("unsupported format: %s version %d", h.Format, h.Version)
+	}
+	var doc *schema.Segment
+	if err := json.Unmarshal(data[idx+1:], &doc); err != nil {
+		return nil, fmt.Errorf("invalid segment document: %w", err)
+	}
+	if doc == nil || doc.TraceID == "" {
+		return nil, errors.New("trace_id is missing")
+	}
+	return doc, nil
+}
+
+func printAll(p printer, viewer *xrayviewer.Viewer, trees []*schema.Segment) {
+	for _, tree := range trees {
+		if viewer != nil {
+			viewer.Add(tree)
+		}
+		if err := p.print(tree); err != nil {
+			log.Printf("failed to print: %v", err)
+		}
+	}
+}
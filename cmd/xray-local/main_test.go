@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDecodePacket(t *testing.T) {
+	doc, err := decodePacket([]byte(`{"format":"json","version":1}` + "\n" + `{"name":"foobar","id":"03babb4ba280be51","trace_id":"1-5e645f3e-1dfad076a177c5ccc5de12f5","start_time":1000000000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Name != "foobar" || doc.TraceID != "1-5e645f3e-1dfad076a177c5ccc5de12f5" {
+		t.Errorf("unexpected document: %#v", doc)
+	}
+
+	invalid := []string{
+		`{"name":"foobar"}`,
+		`{"format":"json","version":2}` + "\n" + `{"name":"foobar","trace_id":"1-5e645f3e-1dfad076a177c5ccc5de12f5"}`,
+		`{"format":"json","version":1}` + "\n" + `{"name":"foobar"}`,
+		`{"format":"json","version":1}` + "\n" + `{`,
+	}
+	for _, data := range invalid {
+		if _, err := decodePacket([]byte(data)); err == nil {
+			t.Errorf("%q: want error, got nil", data)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// printer writes the trace trees.
+type printer interface {
+	print(tree *schema.Segment) error
+}
+
+// jsonPrinter writes the trace trees as JSON lines.
+type jsonPrinter struct {
+	enc *json.Encoder
+}
+
+func newJSONPrinter(w io.Writer) *jsonPrinter {
+	return &jsonPrinter{enc: json.NewEncoder(w)}
+}
+
+func (p *jsonPrinter) print(tree *schema.Segment) error {
+	return p.enc.Encode(tree)
+}
+
+// textPrinter writes the trace trees in human readable format.
+//
+//	1-5e645f3e-1dfad076a177c5ccc5de12f5
+//	└─ my-service  +0.000ms 12.345ms  fault  GET /foo 500  route=/foo
+//	   ├─ dynamodb  +1.000ms 3.000ms  error
+//	   └─ remote  +5.000ms (in progress)
+type textPrinter struct {
+	w io.Writer
+}
+
+func newTextPrinter(w io.Writer) *textPrinter {
+	return &textPrinter{w: w}
+}
+
+func (p *textPrinter) print(tree *schema.Segment) error {
+	var b strings.Builder
+	b.WriteString(tree.TraceID)
+	b.WriteByte('\n')
+	p.writeSegment(&b, tree, tree.StartTime, "", true)
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
+
+func (p *textPrinter) writeSegment(b *strings.Builder, seg *schema.Segment, origin float64, indent string, last bool) {
+	b.WriteString(indent)
+	if last {
+		b.WriteString("└─ ")
+		indent += "   "
+	} else {
+		b.WriteString("├─ ")
+		indent += "│  "
+	}
+	b.WriteString(seg.Name)
+	fmt.Fprintf(b, "  +%.3fms", (seg.StartTime-origin)*1000)
+	if seg.InProgress {
+		b.WriteString(" (in progress)")
+	} else {
+		fmt.Fprintf(b, " %.3fms", (seg.EndTime-seg.StartTime)*1000)
+	}
+
+	var flags []string
+	if seg.Fault {
+		flags = append(flags, "fault")
+	}
+	if seg.Error {
+		flags = append(flags, "error")
+	}
+	if seg.Throttle {
+		flags = append(flags, "throttle")
+	}
+	if len(flags) > 0 {
+		b.WriteString("  ")
+		b.WriteString(strings.Join(flags, ","))
+	}
+
+	if http := seg.HTTP; http != nil {
+		var parts []string
+		if req := http.Request; req != nil {
+			if req.Method != "" {
+				parts = append(parts, req.Method)
+			}
+			if req.URL != "" {
+				parts = append(parts, req.URL)
+			}
+		}
+		if resp := http.Response; resp != nil && resp.Status != 0 {
+			parts = append(parts, fmt.Sprint(resp.Status))
+		}
+		if len(parts) > 0 {
+			b.WriteString("  ")
+			b.WriteString(strings.Join(parts, " "))
+		}
+	}
+
+	if len(seg.Annotations) > 0 {
+		keys := make([]string, 0, len(seg.Annotations))
+		for key := range seg.Annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b.WriteString(" ")
+		for _, key := range keys {
+			fmt.Fprintf(b, " %s=%v", key, seg.Annotations[key])
+		}
+	}
+	b.WriteByte('\n')
+
+	for i, sub := range seg.Subsegments {
+		p.writeSegment(b, sub, origin, indent, i == len(seg.Subsegments)-1)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func TestTextPrinter(t *testing.T) {
+	tree := &schema.Segment{
+		Name:      "my-service",
+		TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		StartTime: 1000000000,
+		EndTime:   1000000000.5,
+		Fault:     true,
+		HTTP: &schema.HTTP{
+			Request: &schema.HTTPRequest{
+				Method: "GET",
+				URL:    "http://example.com/foo",
+			},
+			Response: &schema.HTTPResponse{
+				Status: 500,
+			},
+		},
+		Annotations: map[string]interface{}{
+			"route": "/foo",
+			"count": 42,
+		},
+		Subsegments: []*schema.Segment{
+			{
+				Name:      "dynamodb",
+				StartTime: 1000000000.125,
+				EndTime:   1000000000.25,
+				Error:     true,
+				Throttle:  true,
+				Subsegments: []*schema.Segment{
+					{
+						Name:      "attempt",
+						StartTime: 1000000000.125,
+						EndTime:   1000000000.25,
+					},
+				},
+			},
+			{
+				Name:       "remote",
+				StartTime:  1000000000.25,
+				InProgress: true,
+			},
+		},
+	}
+
+	var buf strings.Builder
+	if err := newTextPrinter(&buf).print(tree); err != nil {
+		t.Fatal(err)
+	}
+	want := `1-5e645f3e-1dfad076a177c5ccc5de12f5
+└─ my-service  +0.000ms 500.000ms  fault  GET http://example.com/foo 500  count=42 route=/foo
+   ├─ dynamodb  +125.000ms 125.000ms  error,throttle
+   │  └─ attempt  +125.000ms 125.000ms
+   └─ remote  +250.000ms (in progress)
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestJSONPrinter(t *testing.T) {
+	tree := &schema.Segment{
+		Name:      "my-service",
+		ID:        "03babb4ba280be51",
+		TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		StartTime: 1000000000,
+		EndTime:   1000000001,
+	}
+	var buf strings.Builder
+	if err := newJSONPrinter(&buf).print(tree); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"my-service","id":"03babb4ba280be51","trace_id":"1-5e645f3e-1dfad076a177c5ccc5de12f5","start_time":1000000000,"end_time":1000000001}` + "\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,242 @@
+// Package nettrace records the subsegments for establishing network connections.
+// It is shared by xrayhttp and xraynet.
+package nettrace
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+// Subsegments records the "dns", "dial" and "tls" subsegments under the segment of the context.
+// net.Dialer may dial several addresses concurrently, so the "dial" subsegments are keyed by the address.
+type Subsegments struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	namespace string
+	dnsSeg    *xray.Segment
+	dials     map[string]*xray.Segment
+	tlsSeg    *xray.Segment
+}
+
+// New returns a new Subsegments. The metadata are recorded in namespace.
+func New(ctx context.Context, namespace string) *Subsegments {
+	return &Subsegments{
+		ctx:       ctx,
+		namespace: namespace,
+		dials:     make(map[string]*xray.Segment),
+	}
+}
+
+// ClientTrace returns the hooks for net.Dialer.
+func (segs *Subsegments) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:     segs.DNSStart,
+		DNSDone:      segs.DNSDone,
+		ConnectStart: segs.ConnectStart,
+		ConnectDone:  segs.ConnectDone,
+	}
+}
+
+// DNSStart begins the "dns" subsegment.
+func (segs *Subsegments) DNSStart(info httptrace.DNSStartInfo) {
+	segs.mu.Lock()
+	defer segs.mu.Unlock()
+	_, segs.dnsSeg = xray.BeginSubsegment(segs.ctx, "dns")
+}
+
+// DNSDone closes the "dns" subsegment.
+func (segs *Subsegments) DNSDone(info httptrace.DNSDoneInfo) {
+	type dnsDoneInfo struct {
+		Addresses []string `json:"addresses"`
+		Coalesced bool     `json:"coalesced"`
+	}
+
+	segs.mu.Lock()
+	defer segs.mu.Unlock()
+	if segs.dnsSeg == nil {
+		return
+	}
+	addresses := make([]string, 0, len(info.Addrs))
+	for _, addr := range info.Addrs {
+		addresses = append(addresses, addr.String())
+	}
+	segs.dnsSeg.AddMetadataToNamespace(segs.namespace, "dns", dnsDoneInfo{
+		Addresses: addresses,
+		Coalesced: info.Coalesced,
+	})
+	segs.dnsSeg.AddError(info.Err)
+	segs.dnsSeg.Close()
+	segs.dnsSeg = nil
+}
+
+// ConnectStart begins the "dial" subsegment.
+func (segs *Subsegments) ConnectStart(network, addr string) {
+	segs.mu.Lock()
+	defer segs.mu.Unlock()
+	_, seg := xray.BeginSubsegment(segs.ctx, "dial")
+	segs.dials[network+" "+addr] = seg
+}
+
+// ConnectDone closes the "dial" subsegment.
+func (segs *Subsegments) ConnectDone(network, addr string, err error) {
+	type dialInfo struct {
+		Network string `json:"network"`
+		Address string `json:"address"`
+	}
+
+	segs.mu.Lock()
+	defer segs.mu.Unlock()
+	key := network + " " + addr
+	seg, ok := segs.dials[key]
+	if !ok {
+		return
+	}
+	delete(segs.dials, key)
+	seg.AddMetadataToNamespace(segs.namespace, "dial", dialInfo{
+		Network: network,
+		Address: addr,
+	})
+	seg.AddError(err)
+	seg.Close()
+}
+
+// TLSHandshakeStart begins the "tls" subsegment.
+func (segs *Subsegments) TLSHandshakeStart() {
+	segs.mu.Lock()
+	defer segs.mu.Unlock()
+	_, segs.tlsSeg = xray.BeginSubsegment(segs.ctx, "tls")
+}
+
+// TLSHandshakeDone closes the "tls" subsegment.
+func (segs *Subsegments) TLSHandshakeDone(state tls.ConnectionState, err error) {
+	type tlsInfo struct {
+		Version                    string `json:"version,omitempty"`
+		DidResume                  bool   `json:"did_resume,omitempty"`
+		NegotiatedProtocol         string `json:"negotiated_protocol,omitempty"`
+		NegotiatedProtocolIsMutual bool   `json:"negotiated_protocol_is_mutual,omitempty"`
+		CipherSuite                string `json:"cipher_suite,omitempty"`
+	}
+
+	segs.mu.Lock()
+	defer segs.mu.Unlock()
+	if segs.tlsSeg == nil {
+		return
+	}
+	if !segs.tlsSeg.AddError(err) {
+		segs.tlsSeg.AddMetadataToNamespace(segs.namespace, "tls", tlsInfo{
+			Version:                    TLSVersionName(state.Version),
+			DidResume:                  state.DidResume,
+			NegotiatedProtocol:         state.NegotiatedProtocol,
+			NegotiatedProtocolIsMutual: state.NegotiatedProtocolIsMutual,
+			CipherSuite:                CipherSuiteName(state.CipherSuite),
+		})
+	}
+	segs.tlsSeg.Close()
+	segs.tlsSeg = nil
+}
+
+// Cancel closes the subsegments that are not closed yet with context.Canceled.
+func (segs *Subsegments) Cancel() {
+	segs.mu.Lock()
+	defer segs.mu.Unlock()
+
+	if segs.dnsSeg != nil {
+		segs.dnsSeg.AddError(context.Canceled)
+		segs.dnsSeg.Close()
+		segs.dnsSeg = nil
+	}
+	for key, seg := range segs.dials {
+		seg.AddError(context.Canceled)
+		seg.Close()
+		delete(segs.dials, key)
+	}
+	if segs.tlsSeg != nil {
+		segs.tlsSeg.AddError(context.Canceled)
+		segs.tlsSeg.Close()
+		segs.tlsSeg = nil
+	}
+}
+
+// TLSVersionName returns the name of the TLS version.
+func TLSVersionName(version uint16) string {
+	switch version {
+	case tls.VersionSSL30:
+		return "ssl3.0"
+	case tls.VersionTLS10:
+		return "tls1.0"
+	case tls.VersionTLS11:
+		return "tls1.1"
+	case tls.VersionTLS12:
+		return "tls1.2"
+	case tls.VersionTLS13:
+		return "tls1.3"
+	}
+
+	// fallback to hex format
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// CipherSuiteName returns the name of the cipher suite.
+// It is a naive implementation of tls.CipherSuiteName from Go 1.14.
+func CipherSuiteName(id uint16) string {
+	switch id {
+	case 0x0005:
+		return "TLS_RSA_WITH_RC4_128_SHA"
+	case 0x000a:
+		return "TLS_RSA_WITH_3DES_EDE_CBC_SHA"
+	case 0x002f:
+		return "TLS_RSA_WITH_AES_128_CBC_SHA"
+	case 0x0035:
+		return "TLS_RSA_WITH_AES_256_CBC_SHA"
+	case 0x003c:
+		return "TLS_RSA_WITH_AES_128_CBC_SHA256"
+	case 0x009c:
+		return "TLS_RSA_WITH_AES_128_GCM_SHA256"
+	case 0x009d:
+		return "TLS_RSA_WITH_AES_256_GCM_SHA384"
+	case 0xc007:
+		return "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA"
+	case 0xc009:
+		return "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA"
+	case 0xc00a:
+		return "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA"
+	case 0xc011:
+		return "TLS_ECDHE_RSA_WITH_RC4_128_SHA"
+	case 0xc012:
+		return "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA"
+	case 0xc013:
+		return "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"
+	case 0xc014:
+		return "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA"
+	case 0xc023:
+		return "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256"
+	case 0xc027:
+		return "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256"
+	case 0xc02f:
+		return "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+	case 0xc02b:
+		return "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"
+	case 0xc030:
+		return "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"
+	case 0xc02c:
+		return "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"
+	case 0xcca8:
+		return "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"
+	case 0xcca9:
+		return "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"
+	case 0x1301:
+		return "TLS_AES_128_GCM_SHA256"
+	case 0x1302:
+		return "TLS_AES_256_GCM_SHA384"
+	case 0x1303:
+		return "TLS_CHACHA20_POLY1305_SHA256"
+	case 0x5600:
+		return "TLS_FALLBACK_SCSV"
+	}
+	return fmt.Sprintf("0x%04X", id)
+}
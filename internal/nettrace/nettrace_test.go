@@ -0,0 +1,72 @@
+package nettrace
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http/httptrace"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestTLSVersionName(t *testing.T) {
+	tests := []struct {
+		version uint16
+		want    string
+	}{
+		{tls.VersionTLS12, "tls1.2"},
+		{tls.VersionTLS13, "tls1.3"},
+		{0x0305, "0x0305"},
+	}
+	for _, tt := range tests {
+		if got := TLSVersionName(tt.version); got != tt.want {
+			t.Errorf("TLSVersionName(0x%04X): want %q, got %q", tt.version, tt.want, got)
+		}
+	}
+}
+
+func TestCipherSuiteName(t *testing.T) {
+	if got := CipherSuiteName(tls.TLS_AES_128_GCM_SHA256); got != "TLS_AES_128_GCM_SHA256" {
+		t.Errorf("want %q, got %q", "TLS_AES_128_GCM_SHA256", got)
+	}
+	if got := CipherSuiteName(0xFFFF); got != "0xFFFF" {
+		t.Errorf("want %q, got %q", "0xFFFF", got)
+	}
+}
+
+func TestSubsegments(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	func() {
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+
+		segs := New(ctx, "net")
+		segs.DNSStart(httptrace.DNSStartInfo{Host: "example.com"})
+		segs.DNSDone(httptrace.DNSDoneInfo{})
+		segs.ConnectStart("tcp", "192.0.2.1:80")
+		segs.ConnectStart("tcp", "192.0.2.2:80")
+		segs.ConnectDone("tcp", "192.0.2.2:80", errors.New("connection refused"))
+		segs.Cancel()
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, sub := range got.Subsegments {
+		names = append(names, sub.Name)
+	}
+	if diff := cmp.Diff([]string{"dns", "dial", "dial"}, names); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	// the failed dial and the canceled dial are faults.
+	for _, sub := range got.Subsegments[1:] {
+		if !sub.Fault {
+			t.Errorf("want fault, got %#v", sub)
+		}
+	}
+}
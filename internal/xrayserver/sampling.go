@@ -0,0 +1,378 @@
+// Package xrayserver implements the fakes of the server side of AWS X-Ray, e.g. the centralized sampling API and the assembly of trace trees.
+// It is shared by the xraytest package and the xray-local command, and doesn't depend on the testing package.
+package xrayserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+// SamplingRule is a sampling rule served by SamplingServer.
+// https://docs.aws.amazon.com/xray/latest/api/API_SamplingRule.html
+type SamplingRule struct {
+	RuleName      string            `json:"RuleName"`
+	RuleARN       string            `json:"RuleARN"`
+	ResourceARN   string            `json:"ResourceARN"`
+	Priority      int64             `json:"Priority"`
+	FixedRate     float64           `json:"FixedRate"`
+	ReservoirSize int64             `json:"ReservoirSize"`
+	ServiceName   string            `json:"ServiceName"`
+	ServiceType   string            `json:"ServiceType"`
+	Host          string            `json:"Host"`
+	HTTPMethod    string            `json:"HTTPMethod"`
+	URLPath       string            `json:"URLPath"`
+	Version       int64             `json:"Version"`
+	Attributes    map[string]string `json:"Attributes"`
+}
+
+// DefaultSamplingRule is the default rule of AWS X-Ray.
+var DefaultSamplingRule = SamplingRule{
+	RuleName:      "Default",
+	RuleARN:       "arn:aws:xray:us-east-1:123456789012:sampling-rule/Default",
+	ResourceARN:   "*",
+	Priority:      10000,
+	FixedRate:     0.05,
+	ReservoirSize: 1,
+	ServiceName:   "*",
+	ServiceType:   "*",
+	Host:          "*",
+	HTTPMethod:    "*",
+	URLPath:       "*",
+	Version:       1,
+	Attributes:    map[string]string{},
+}
+
+// SamplingTarget is the quota of a rule served by SamplingServer.
+type SamplingTarget struct {
+	// The percentage of matching requests to instrument, after the reservoir is exhausted.
+	FixedRate float64
+
+	// The number of requests per second that X-Ray allocated this service.
+	ReservoirQuota int64
+
+	// When the reservoir quota expires.
+	// If it is zero, the quota expires after one minute.
+	ReservoirQuotaTTL time.Time
+
+	// The number of seconds for the service to wait before getting sampling targets again.
+	Interval int64
+}
+
+// SamplingStatistics is the statistics received by SamplingServer.
+// https://docs.aws.amazon.com/xray/latest/api/API_SamplingStatisticsDocument.html
+type SamplingStatistics struct {
+	ClientID     string `json:"ClientID"`
+	RuleName     string `json:"RuleName"`
+	RequestCount int64  `json:"RequestCount"`
+	SampledCount int64  `json:"SampledCount"`
+	BorrowCount  int64  `json:"BorrowCount"`
+	Timestamp    string `json:"Timestamp"`
+}
+
+// SamplingServer is a fake of the centralized sampling API served by AWS X-Ray daemon.
+// It serves GetSamplingRules and GetSamplingTargets.
+//
+//	server := xraytest.NewSamplingServer()
+//	server.SetRules(xraytest.SamplingRule{RuleName: "my-rule", ...}, xraytest.DefaultSamplingRule)
+//	ctx, td := xraytest.NewDaemon(server)
+type SamplingServer struct {
+	// PageSize is the maximum number of rules in a page of GetSamplingRules.
+	// If it is zero, all rules are returned in one page.
+	PageSize int
+
+	mu               sync.Mutex
+	rules            []SamplingRule
+	targets          map[string]SamplingTarget
+	lastModification int64
+	statistics       []SamplingStatistics
+	failRules        int
+	failTargets      int
+	rulesRequests    int
+	targetsRequests  int
+}
+
+// NewSamplingServer returns a new SamplingServer that serves DefaultSamplingRule.
+func NewSamplingServer() *SamplingServer {
+	return &SamplingServer{
+		rules:            []SamplingRule{DefaultSamplingRule},
+		targets:          make(map[string]SamplingTarget),
+		lastModification: time.Now().Unix(),
+	}
+}
+
+// SetRules replaces the sampling rules, and bumps LastRuleModification.
+func (s *SamplingServer) SetRules(rules ...SamplingRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append([]SamplingRule(nil), rules...)
+	s.bumpRuleModification()
+}
+
+// SetTarget sets the quota of the rule.
+// If the target of a rule is not set, the quota is calculated from the rule.
+func (s *SamplingServer) SetTarget(ruleName string, target SamplingTarget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets[ruleName] = target
+}
+
+// BumpRuleModification updates LastRuleModification,
+// and makes the clients refresh the sampling rules on the next GetSamplingTargets.
+func (s *SamplingServer) BumpRuleModification() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bumpRuleModification()
+}
+
+func (s *SamplingServer) bumpRuleModification() {
+	// LastRuleModification is in seconds, round it up
+	// so that it is after the time when the clients refreshed the rules.
+	now := time.Now().Unix() + 1
+	if now <= s.lastModification {
+		now = s.lastModification + 1
+	}
+	s.lastModification = now
+}
+
+// FailRules makes the next n requests of GetSamplingRules fail.
+// If n is negative, all requests fail until FailRules(0) is called.
+func (s *SamplingServer) FailRules(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failRules = n
+}
+
+// FailTargets makes the next n requests of GetSamplingTargets fail.
+// If n is negative, all requests fail until FailTargets(0) is called.
+func (s *SamplingServer) FailTargets(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failTargets = n
+}
+
+// Statistics returns the statistics documents that the server received.
+func (s *SamplingServer) Statistics() []SamplingStatistics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SamplingStatistics(nil), s.statistics...)
+}
+
+// RulesRequests returns the number of GetSamplingRules requests, including failed ones.
+func (s *SamplingServer) RulesRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rulesRequests
+}
+
+// TargetsRequests returns the number of GetSamplingTargets requests, including failed ones.
+func (s *SamplingServer) TargetsRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.targetsRequests
+}
+
+// ServeHTTP implements http.Handler.
+func (s *SamplingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch r.URL.Path {
+	case "/GetSamplingRules":
+		s.serveRules(w, r)
+	case "/SamplingTargets":
+		s.serveTargets(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func shouldFail(n *int) bool {
+	if *n == 0 {
+		return false
+	}
+	if *n > 0 {
+		*n--
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *SamplingServer) serveRules(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		NextToken string `json:"NextToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rulesRequests++
+	if shouldFail(&s.failRules) {
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	}
+
+	start := 0
+	if input.NextToken != "" {
+		var err error
+		start, err = strconv.Atoi(input.NextToken)
+		if err != nil || start < 0 || start > len(s.rules) {
+			http.Error(w, "invalid next token", http.StatusBadRequest)
+			return
+		}
+	}
+	end := len(s.rules)
+	if s.PageSize > 0 && start+s.PageSize < end {
+		end = start + s.PageSize
+	}
+
+	type record struct {
+		CreatedAt    float64      `json:"CreatedAt"`
+		ModifiedAt   float64      `json:"ModifiedAt"`
+		SamplingRule SamplingRule `json:"SamplingRule"`
+	}
+	var output struct {
+		NextToken           string    `json:"NextToken,omitempty"`
+		SamplingRuleRecords []*record `json:"SamplingRuleRecords"`
+	}
+	for _, rule := range s.rules[start:end] {
+		output.SamplingRuleRecords = append(output.SamplingRuleRecords, &record{
+			CreatedAt:    float64(s.lastModification),
+			ModifiedAt:   float64(s.lastModification),
+			SamplingRule: rule,
+		})
+	}
+	if end < len(s.rules) {
+		output.NextToken = strconv.Itoa(end)
+	}
+	writeJSON(w, output)
+}
+
+func (s *SamplingServer) serveTargets(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		SamplingStatisticsDocuments []SamplingStatistics `json:"SamplingStatisticsDocuments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targetsRequests++
+	if shouldFail(&s.failTargets) {
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	}
+	s.statistics = append(s.statistics, input.SamplingStatisticsDocuments...)
+
+	type targetDocument struct {
+		FixedRate         float64 `json:"FixedRate"`
+		Interval          int64   `json:"Interval"`
+		ReservoirQuota    int64   `json:"ReservoirQuota"`
+		ReservoirQuotaTTL string  `json:"ReservoirQuotaTTL"`
+		RuleName          string  `json:"RuleName"`
+	}
+	type unprocessedStatistics struct {
+		ErrorCode string `json:"ErrorCode"`
+		Message   string `json:"Message"`
+		RuleName  string `json:"RuleName"`
+	}
+	var output struct {
+		LastRuleModification    int64                    `json:"LastRuleModification"`
+		SamplingTargetDocuments []*targetDocument        `json:"SamplingTargetDocuments"`
+		UnprocessedStatistics   []*unprocessedStatistics `json:"UnprocessedStatistics"`
+	}
+	output.LastRuleModification = s.lastModification
+	for _, stat := range input.SamplingStatisticsDocuments {
+		target, ok := s.targets[stat.RuleName]
+		if !ok {
+			rule, ok := s.findRule(stat.RuleName)
+			if !ok {
+				output.UnprocessedStatistics = append(output.UnprocessedStatistics, &unprocessedStatistics{
+					ErrorCode: "400",
+					Message:   "Unknown rule",
+					RuleName:  stat.RuleName,
+				})
+				continue
+			}
+			target = SamplingTarget{
+				FixedRate:      rule.FixedRate,
+				ReservoirQuota: rule.ReservoirSize,
+			}
+		}
+		ttl := target.ReservoirQuotaTTL
+		if ttl.IsZero() {
+			ttl = time.Now().Add(time.Minute)
+		}
+		output.SamplingTargetDocuments = append(output.SamplingTargetDocuments, &targetDocument{
+			FixedRate:         target.FixedRate,
+			Interval:          target.Interval,
+			ReservoirQuota:    target.ReservoirQuota,
+			ReservoirQuotaTTL: ttl.UTC().Format(time.RFC3339),
+			RuleName:          stat.RuleName,
+		})
+	}
+	writeJSON(w, output)
+}
+
+func (s *SamplingServer) findRule(name string) (SamplingRule, bool) {
+	for _, rule := range s.rules {
+		if rule.RuleName == name {
+			return rule, true
+		}
+	}
+	return SamplingRule{}, false
+}
+
+// SamplingRulesFromManifest converts the local sampling rules into the centralized sampling rules.
+// The rules are named by their description, or "rule-N" if the description is empty.
+// The empty patterns match any values.
+func SamplingRulesFromManifest(manifest *sampling.Manifest) []SamplingRule {
+	pattern := func(s string) string {
+		if s == "" {
+			return "*"
+		}
+		return s
+	}
+	rules := make([]SamplingRule, 0, len(manifest.Rules)+1)
+	for i, r := range manifest.Rules {
+		name := r.Description
+		if name == "" {
+			name = "rule-" + strconv.Itoa(i+1)
+		}
+		rules = append(rules, SamplingRule{
+			RuleName:      name,
+			ResourceARN:   "*",
+			Priority:      int64(i + 1),
+			FixedRate:     r.Rate,
+			ReservoirSize: r.FixedTarget,
+			ServiceName:   pattern(r.ServiceName),
+			ServiceType:   "*",
+			Host:          pattern(r.Host),
+			HTTPMethod:    pattern(r.HTTPMethod),
+			URLPath:       pattern(r.URLPath),
+			Version:       1,
+			Attributes:    map[string]string{},
+		})
+	}
+	if manifest.Default != nil {
+		rule := DefaultSamplingRule
+		rule.FixedRate = manifest.Default.Rate
+		rule.ReservoirSize = manifest.Default.FixedTarget
+		rules = append(rules, rule)
+	}
+	return rules
+}
@@ -0,0 +1,241 @@
+package xrayserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+func post(t *testing.T, url string, in, out interface{}) int {
+	t.Helper()
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestSamplingServer_GetSamplingRules(t *testing.T) {
+	server := NewSamplingServer()
+	server.PageSize = 1
+	rule := SamplingRule{
+		RuleName:      "my-rule",
+		Priority:      1,
+		FixedRate:     0.5,
+		ReservoirSize: 10,
+		ServiceName:   "my-service",
+		ServiceType:   "*",
+		Host:          "*",
+		HTTPMethod:    "*",
+		URLPath:       "*",
+	}
+	server.SetRules(rule, DefaultSamplingRule)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	type output struct {
+		NextToken           string
+		SamplingRuleRecords []struct {
+			SamplingRule SamplingRule
+		}
+	}
+	var got []SamplingRule
+	var token string
+	for {
+		var out output
+		if status := post(t, ts.URL+"/GetSamplingRules", map[string]string{"NextToken": token}, &out); status != http.StatusOK {
+			t.Fatalf("unexpected status: %d", status)
+		}
+		for _, r := range out.SamplingRuleRecords {
+			got = append(got, r.SamplingRule)
+		}
+		if out.NextToken == "" {
+			break
+		}
+		token = out.NextToken
+	}
+	if diff := cmp.Diff([]SamplingRule{rule, DefaultSamplingRule}, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if got := server.RulesRequests(); got != 2 {
+		t.Errorf("want %d, got %d", 2, got)
+	}
+
+	// failure injection
+	server.FailRules(1)
+	var out output
+	if status := post(t, ts.URL+"/GetSamplingRules", struct{}{}, &out); status != http.StatusInternalServerError {
+		t.Errorf("want %d, got %d", http.StatusInternalServerError, status)
+	}
+	if status := post(t, ts.URL+"/GetSamplingRules", struct{}{}, &out); status != http.StatusOK {
+		t.Errorf("want %d, got %d", http.StatusOK, status)
+	}
+}
+
+func TestSamplingServer_SamplingTargets(t *testing.T) {
+	server := NewSamplingServer()
+	ttl := time.Unix(1000000000, 0)
+	server.SetTarget("my-rule", SamplingTarget{
+		FixedRate:         0.1,
+		ReservoirQuota:    5,
+		ReservoirQuotaTTL: ttl,
+		Interval:          10,
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	stats := []SamplingStatistics{
+		{ClientID: "client", RuleName: "my-rule", RequestCount: 10, SampledCount: 5, BorrowCount: 1, Timestamp: "2001-09-09T01:46:40Z"},
+		{ClientID: "client", RuleName: "Default", RequestCount: 1, SampledCount: 1, Timestamp: "2001-09-09T01:46:40Z"},
+		{ClientID: "client", RuleName: "unknown", RequestCount: 1, Timestamp: "2001-09-09T01:46:40Z"},
+	}
+	var out struct {
+		LastRuleModification    int64
+		SamplingTargetDocuments []struct {
+			FixedRate         float64
+			Interval          int64
+			ReservoirQuota    int64
+			ReservoirQuotaTTL string
+			RuleName          string
+		}
+		UnprocessedStatistics []struct {
+			RuleName string
+		}
+	}
+	before := server.lastModification
+	server.BumpRuleModification()
+	if status := post(t, ts.URL+"/SamplingTargets", map[string]interface{}{"SamplingStatisticsDocuments": stats}, &out); status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", status)
+	}
+
+	if out.LastRuleModification <= before || out.LastRuleModification <= time.Now().Unix() {
+		t.Errorf("LastRuleModification is not bumped: %d", out.LastRuleModification)
+	}
+	if len(out.SamplingTargetDocuments) != 2 {
+		t.Fatalf("unexpected targets: %#v", out.SamplingTargetDocuments)
+	}
+	if doc := out.SamplingTargetDocuments[0]; doc.RuleName != "my-rule" || doc.ReservoirQuota != 5 || doc.ReservoirQuotaTTL != "2001-09-09T01:46:40Z" {
+		t.Errorf("unexpected target: %#v", doc)
+	}
+	if doc := out.SamplingTargetDocuments[1]; doc.RuleName != "Default" || doc.ReservoirQuota != 1 || doc.FixedRate != 0.05 {
+		t.Errorf("unexpected target: %#v", doc)
+	}
+	if len(out.UnprocessedStatistics) != 1 || out.UnprocessedStatistics[0].RuleName != "unknown" {
+		t.Errorf("unexpected unprocessed statistics: %#v", out.UnprocessedStatistics)
+	}
+	if diff := cmp.Diff(stats, server.Statistics()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSamplingServer_CentralizedStrategy(t *testing.T) {
+	server := NewSamplingServer()
+	server.SetRules(SamplingRule{
+		RuleName:    "my-rule",
+		Priority:    1,
+		ServiceName: "my-service",
+		ServiceType: "*",
+		Host:        "*",
+		HTTPMethod:  "*",
+		URLPath:     "*",
+	}, DefaultSamplingRule)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	s, err := sampling.NewCentralizedStrategy(strings.TrimPrefix(ts.URL, "http://"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d := s.ShouldTrace(&sampling.Request{ServiceName: "my-service"})
+		if d.Rule != nil && *d.Rule == "my-rule" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the rule is not applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if server.RulesRequests() == 0 {
+		t.Error("want GetSamplingRules requests, got none")
+	}
+}
+
+func TestSamplingRulesFromManifest(t *testing.T) {
+	manifest := &sampling.Manifest{
+		Version: 2,
+		Default: &sampling.Rule{
+			FixedTarget: 2,
+			Rate:        0.1,
+		},
+		Rules: []*sampling.Rule{
+			{
+				Description: "health check",
+				URLPath:     "/health",
+				FixedTarget: 0,
+				Rate:        0,
+			},
+			{
+				Host:        "example.com",
+				FixedTarget: 1,
+				Rate:        0.5,
+			},
+		},
+	}
+	got := SamplingRulesFromManifest(manifest)
+	want := []SamplingRule{
+		{
+			RuleName:    "health check",
+			ResourceARN: "*",
+			Priority:    1,
+			ServiceName: "*",
+			ServiceType: "*",
+			Host:        "*",
+			HTTPMethod:  "*",
+			URLPath:     "/health",
+			Version:     1,
+			Attributes:  map[string]string{},
+		},
+		{
+			RuleName:      "rule-2",
+			ResourceARN:   "*",
+			Priority:      2,
+			FixedRate:     0.5,
+			ReservoirSize: 1,
+			ServiceName:   "*",
+			ServiceType:   "*",
+			Host:          "example.com",
+			HTTPMethod:    "*",
+			URLPath:       "*",
+			Version:       1,
+			Attributes:    map[string]string{},
+		},
+		DefaultSamplingRule,
+	}
+	want[2].FixedRate = 0.1
+	want[2].ReservoirSize = 2
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,94 @@
+package xrayserver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+type node struct {
+	seg      *schema.Segment
+	parentID string
+	children []string
+	order    int
+}
+
+// Tree reconstructs the trace tree from the segment documents of a trace.
+// The documents may be streamed separately, they are linked by their parent_id.
+// If the same segment is found more than once, the completed one is used.
+// The subsegments are sorted by their start time.
+func Tree(docs []*schema.Segment) (*schema.Segment, error) {
+	nodes := map[string]*node{}
+	var order int
+	var visit func(seg *schema.Segment, parentID string)
+	visit = func(seg *schema.Segment, parentID string) {
+		copied := *seg
+		copied.Subsegments = nil
+		if n, ok := nodes[seg.ID]; ok {
+			// the segment is streamed more than once. prefer the completed one.
+			if n.seg.InProgress && !seg.InProgress {
+				n.seg = &copied
+			}
+			if n.parentID == "" {
+				n.parentID = parentID
+			}
+		} else {
+			order++
+			nodes[seg.ID] = &node{
+				seg:      &copied,
+				parentID: parentID,
+				order:    order,
+			}
+		}
+		for _, sub := range seg.Subsegments {
+			visit(sub, seg.ID)
+		}
+	}
+	for _, doc := range docs {
+		visit(doc, doc.ParentID)
+	}
+
+	// link the segments.
+	var roots []*node
+	for id, n := range nodes {
+		if parent, ok := nodes[n.parentID]; ok && n.parentID != id {
+			parent.children = append(parent.children, id)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("xrayserver: the root segment is not found")
+	}
+	if len(roots) > 1 {
+		return nil, fmt.Errorf("xrayserver: found %d root segments", len(roots))
+	}
+
+	var build func(n *node, isRoot bool) *schema.Segment
+	build = func(n *node, isRoot bool) *schema.Segment {
+		seg := n.seg
+		if !isRoot {
+			// the subsegment is nested in its parent.
+			seg.TraceID = ""
+			seg.ParentID = ""
+			seg.Type = ""
+		}
+		children := make([]*node, 0, len(n.children))
+		for _, id := range n.children {
+			children = append(children, nodes[id])
+		}
+		sort.Slice(children, func(i, j int) bool {
+			if children[i].seg.StartTime != children[j].seg.StartTime {
+				return children[i].seg.StartTime < children[j].seg.StartTime
+			}
+			return children[i].order < children[j].order
+		})
+		for _, child := range children {
+			seg.Subsegments = append(seg.Subsegments, build(child, false))
+		}
+		return seg
+	}
+	return build(roots[0], true), nil
+}
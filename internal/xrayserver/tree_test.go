@@ -0,0 +1,97 @@
+package xrayserver
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func TestTree(t *testing.T) {
+	docs := []*schema.Segment{
+		// the subsegment is streamed separately.
+		{
+			Name:      "sub2",
+			ID:        "0000000000000003",
+			TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+			ParentID:  "0000000000000001",
+			Type:      "subsegment",
+			StartTime: 1000000002,
+			EndTime:   1000000003,
+		},
+		// the root segment is in progress.
+		{
+			Name:       "root",
+			ID:         "0000000000000001",
+			TraceID:    "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+			StartTime:  1000000000,
+			InProgress: true,
+		},
+		{
+			Name:      "root",
+			ID:        "0000000000000001",
+			TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+			StartTime: 1000000000,
+			EndTime:   1000000004,
+			Subsegments: []*schema.Segment{
+				{
+					Name:      "sub1",
+					ID:        "0000000000000002",
+					StartTime: 1000000001,
+					EndTime:   1000000002,
+				},
+			},
+		},
+	}
+	got, err := Tree(docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &schema.Segment{
+		Name:      "root",
+		ID:        "0000000000000001",
+		TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		StartTime: 1000000000,
+		EndTime:   1000000004,
+		Subsegments: []*schema.Segment{
+			{
+				Name:      "sub1",
+				ID:        "0000000000000002",
+				StartTime: 1000000001,
+				EndTime:   1000000002,
+			},
+			{
+				Name:      "sub2",
+				ID:        "0000000000000003",
+				StartTime: 1000000002,
+				EndTime:   1000000003,
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTree_MultipleRoots(t *testing.T) {
+	docs := []*schema.Segment{
+		{
+			Name:     "sub",
+			ID:       "0000000000000002",
+			TraceID:  "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+			ParentID: "0000000000000001",
+			Type:     "subsegment",
+		},
+		{
+			Name:    "other",
+			ID:      "0000000000000003",
+			TraceID: "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		},
+	}
+	if _, err := Tree(docs); err == nil {
+		t.Error("want error, got nil")
+	}
+	if _, err := Tree(nil); err == nil {
+		t.Error("want error, got nil")
+	}
+}
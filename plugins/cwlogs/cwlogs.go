@@ -13,6 +13,7 @@
 //    xray.AddPlugin(plugin)
 //
 // And then, you need to add X-Ray Trace ID into your log.
+// The tracelog package (github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog/tracelog) helps it.
 package cwlogs
 
 import (
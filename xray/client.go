@@ -7,6 +7,7 @@ import (
 	"net"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/ctxmissing"
@@ -17,6 +18,9 @@ import (
 
 const emitTimeout = 100 * time.Millisecond
 
+// drainTimeout is the maximum duration that Configure waits for the segments of the old client.
+const drainTimeout = time.Minute
+
 var header = []byte(`{"format":"json","version":1}` + "\n")
 var dialer = net.Dialer{
 	Timeout: emitTimeout,
@@ -25,10 +29,12 @@ var dialer = net.Dialer{
 var defaultClient = New(nil)
 
 // Configure relaces the default client with the cfg.
+// The old client is closed after all of its segments are closed, or after one minute.
 func Configure(cfg *Config) {
 	client := New(cfg)
-	defaultClient.Close()
+	old := defaultClient
 	defaultClient = client
+	old.drain(drainTimeout)
 }
 
 // ContextClient returns the client of current context.
@@ -55,9 +61,29 @@ type Client struct {
 	streamingStrategy      StreamingStrategy
 	samplingStrategy       sampling.Strategy
 	contextMissingStrategy ctxmissing.Strategy
+	idGenerator            IDGenerator
+	metricsRecorder        MetricsRecorder
+	recordUnsampled        bool
+	emitter                Emitter
+
+	// the sampling strategy created by the client.
+	// it is closed when the client is closed.
+	ownedSamplingStrategy *sampling.CentralizedStrategy
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
 
-	mu   sync.Mutex
-	conn net.Conn
+	// the root segments that are not closed yet.
+	muSegments sync.Mutex
+	segments   map[*Segment]struct{}
+
+	// the client is closed when all root segments are closed, or drainTimer fires.
+	draining   bool
+	drainTimer *time.Timer
+
+	// the internal metrics of the client.
+	stats *clientStats
 }
 
 // New returns a new Client.
@@ -65,6 +91,7 @@ func New(config *Config) *Client {
 	// initialize sampling strategy
 	p := config.daemonEndpoints()
 	var samplingStrategy sampling.Strategy
+	var ownedSamplingStrategy *sampling.CentralizedStrategy
 	var contextMissingStrategy ctxmissing.Strategy
 	if config != nil {
 		samplingStrategy = config.SamplingStrategy
@@ -72,10 +99,11 @@ func New(config *Config) *Client {
 	}
 	if samplingStrategy == nil {
 		var err error
-		samplingStrategy, err = sampling.NewCentralizedStrategy(p.TCP, nil)
+		ownedSamplingStrategy, err = sampling.NewCentralizedStrategy(p.TCP, nil)
 		if err != nil {
 			panic(err)
 		}
+		samplingStrategy = ownedSamplingStrategy
 	}
 	if contextMissingStrategy == nil {
 		switch os.Getenv("AWS_XRAY_CONTEXT_MISSING") {
@@ -83,11 +111,30 @@ func New(config *Config) *Client {
 			contextMissingStrategy = &ctxmissing.LogErrorStrategy{}
 		case "RUNTIME_ERROR":
 			contextMissingStrategy = &ctxmissing.RuntimeErrorStrategy{}
+		case "IGNORE_ERROR":
+			contextMissingStrategy = &ctxmissing.IgnoreErrorStrategy{}
 		default:
 			contextMissingStrategy = &ctxmissing.LogErrorStrategy{}
 		}
 	}
 
+	idGenerator := defaultIDGenerator
+	if config != nil && config.IDGenerator != nil {
+		idGenerator = config.IDGenerator
+	}
+
+	var metricsRecorder MetricsRecorder
+	var recordUnsampled bool
+	if config != nil && config.MetricsRecorder != nil {
+		metricsRecorder = config.MetricsRecorder
+		recordUnsampled = config.RecordUnsampledMetrics
+	}
+
+	var emitter Emitter
+	if config != nil {
+		emitter = config.Emitter
+	}
+
 	// initialize streaming strategy
 	streamingStrategy := NewStreamingStrategyLimitSubsegment(20)
 	if config != nil && config.StreamingStrategy != nil {
@@ -105,6 +152,13 @@ func New(config *Config) *Client {
 		streamingStrategy:      streamingStrategy,
 		samplingStrategy:       samplingStrategy,
 		contextMissingStrategy: contextMissingStrategy,
+		idGenerator:            idGenerator,
+		metricsRecorder:        metricsRecorder,
+		recordUnsampled:        recordUnsampled,
+		emitter:                emitter,
+		ownedSamplingStrategy:  ownedSamplingStrategy,
+		segments:               make(map[*Segment]struct{}),
+		stats:                  new(clientStats),
 	}
 	return client
 }
@@ -120,6 +174,10 @@ func (c *Client) emit(ctx context.Context, seg *schema.Segment) {
 	if c.disabled {
 		return
 	}
+	if c.emitter != nil {
+		c.emitToEmitter(ctx, seg)
+		return
+	}
 
 	buf := c.pool.Get().(*bytes.Buffer)
 	defer c.pool.Put(buf)
@@ -127,34 +185,69 @@ func (c *Client) emit(ctx context.Context, seg *schema.Segment) {
 	buf.Write(header)
 	enc := json.NewEncoder(buf)
 	if err := enc.Encode(seg); err != nil {
-		xraylog.Errorf(ctx, "failed to encode: %v", err)
+		atomic.AddUint64(&c.stats.encodeErrors, 1)
+		xraylog.ErrorAttrs(ctx, "failed to encode", xraylog.Err(err), xraylog.String("trace_id", seg.TraceID), xraylog.String("segment_id", seg.ID))
 		return
 	}
 	xraylog.Debugf(ctx, "emit: %s", buf.Bytes()[len(header):])
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.closed {
+		xraylog.ErrorAttrs(ctx, "failed to emit: the client is already closed", xraylog.String("trace_id", seg.TraceID), xraylog.String("segment_id", seg.ID))
+		return
+	}
 	if c.conn == nil {
 		emitCtx, cancel := context.WithTimeout(context.Background(), emitTimeout)
 		defer cancel()
 
 		conn, err := dialer.DialContext(emitCtx, "udp", c.udp)
 		if err != nil {
-			xraylog.Errorf(ctx, "failed to dial: %v", err)
+			atomic.AddUint64(&c.stats.dialErrors, 1)
+			xraylog.ErrorAttrs(ctx, "failed to dial", xraylog.Err(err), xraylog.String("address", c.udp))
 			return
 		}
 		c.conn = conn
 	}
-	if _, err := c.conn.Write(buf.Bytes()); err != nil {
-		xraylog.Errorf(ctx, "failed to write: %v", err)
+	n, err := c.conn.Write(buf.Bytes())
+	atomic.AddUint64(&c.stats.bytesSent, uint64(n))
+	if err != nil {
+		atomic.AddUint64(&c.stats.writeErrors, 1)
+		xraylog.ErrorAttrs(ctx, "failed to write", xraylog.Err(err), xraylog.String("trace_id", seg.TraceID), xraylog.String("segment_id", seg.ID))
 		return
 	}
+	atomic.AddUint64(&c.stats.segmentsEmitted, 1)
 }
 
 // Close closes the client.
+// It flushes the segments that are not closed yet as in-progress segments,
+// and stops the sampling strategy created by the client.
+// The strategies given by Config are not stopped, the caller should stop them.
 func (c *Client) Close() error {
+	ctx := context.Background()
+
+	// flush the segments that are not closed yet.
+	c.muSegments.Lock()
+	segments := c.segments
+	c.segments = nil // the segments are not tracked after the client is closed.
+	c.muSegments.Unlock()
+	for seg := range segments {
+		xraylog.WarnAttrs(ctx, "segment is not closed at shutdown", xraylog.String("segment_name", seg.name), xraylog.String("trace_id", seg.traceID))
+		for _, data := range c.streamInProgress(seg) {
+			c.emit(ctx, data)
+		}
+	}
+
+	if c.ownedSamplingStrategy != nil {
+		c.ownedSamplingStrategy.Close()
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
 	if c.conn != nil {
 		err := c.conn.Close()
 		c.conn = nil
@@ -162,3 +255,77 @@ func (c *Client) Close() error {
 	}
 	return nil
 }
+
+// streamInProgress serializes the root segment that is not closed yet through the streaming strategy.
+func (c *Client) streamInProgress(root *Segment) []*schema.Segment {
+	root.mu.RLock()
+	closed := !root.inProgress()
+	root.mu.RUnlock()
+	if closed {
+		// the segment is being emitted by Close of the segment.
+		return nil
+	}
+
+	result := c.streamingStrategy.StreamSegment(root)
+	for _, data := range result {
+		if data.ID == root.id {
+			return result
+		}
+	}
+
+	// the strategy sends only the completed subsegments, send the rest independently.
+	return append(result, serializeRemaining(root)...)
+}
+
+// drain closes the client after all root segments are closed.
+// If some of them are not closed within timeout, e.g. they are leaked, the client is closed anyway.
+func (c *Client) drain(timeout time.Duration) {
+	c.muSegments.Lock()
+	idle := len(c.segments) == 0
+	if !idle && !c.draining {
+		c.draining = true
+		c.drainTimer = time.AfterFunc(timeout, c.closeDrained)
+	}
+	c.muSegments.Unlock()
+	if idle {
+		c.Close()
+	}
+}
+
+// closeDrained closes the draining client whose segments are not closed in time.
+func (c *Client) closeDrained() {
+	c.muSegments.Lock()
+	draining := c.draining
+	c.draining = false
+	c.muSegments.Unlock()
+	if draining {
+		xraylog.Warn(context.Background(), "the segments of the old client are not closed in time, close it anyway")
+		c.Close()
+	}
+}
+
+// addSegment registers the root segment that is not closed yet.
+func (c *Client) addSegment(seg *Segment) {
+	c.muSegments.Lock()
+	defer c.muSegments.Unlock()
+	if c.segments == nil {
+		// the client is already closed.
+		return
+	}
+	c.segments[seg] = struct{}{}
+}
+
+// removeSegment unregisters the root segment.
+func (c *Client) removeSegment(seg *Segment) {
+	c.muSegments.Lock()
+	delete(c.segments, seg)
+	idle := c.draining && len(c.segments) == 0
+	if idle {
+		c.draining = false
+		c.drainTimer.Stop()
+	}
+	c.muSegments.Unlock()
+	if idle {
+		c.Close()
+	}
+}
@@ -1,9 +1,16 @@
 package xray
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
 )
 
 func BenchmarkClient(b *testing.B) {
@@ -25,3 +32,178 @@ func BenchmarkClient(b *testing.B) {
 		client.emit(ctx, seg)
 	}
 }
+
+func TestClient_Close(t *testing.T) {
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+
+	ctx, seg := BeginSegment(ctx, "foobar")
+	_ = ctx // do something using ctx
+
+	// the segment is not closed yet, it is flushed as in-progress.
+	if err := ContextClient(ctx).Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != seg.id {
+		t.Errorf("want %s, got %s", seg.id, got.ID)
+	}
+	if !got.InProgress {
+		t.Error("want in progress, but not")
+	}
+
+	// the client is already closed, the segment is not emitted.
+	seg.Close()
+	if got, err := td.Recv(); err == nil {
+		t.Errorf("want error, got %v", got)
+	}
+
+	// Close is idempotent.
+	if err := ContextClient(ctx).Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_CloseStreamed(t *testing.T) {
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+	client := ContextClient(ctx)
+	client.streamingStrategy = NewStreamingStrategyLimitSubsegment(1)
+
+	ctx, seg := BeginSegment(ctx, "foobar")
+	streamed := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		_, sub := BeginSubsegment(ctx, "streamed")
+		sub.Close()
+		streamed[sub.id] = true
+	}
+	_, open := BeginSubsegment(ctx, "open")
+	for range streamed {
+		if _, err := td.Recv(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the subsegments already streamed are not sent again.
+	got := map[string]bool{}
+	for {
+		doc, err := td.Recv()
+		if err != nil {
+			break
+		}
+		var walk func(doc *schema.Segment)
+		walk = func(doc *schema.Segment) {
+			if streamed[doc.ID] {
+				t.Errorf("%s is sent twice", doc.ID)
+			}
+			got[doc.ID] = doc.InProgress
+			for _, sub := range doc.Subsegments {
+				walk(sub)
+			}
+		}
+		walk(doc)
+	}
+	if inProgress, ok := got[seg.id]; !ok || !inProgress {
+		t.Errorf("want the root segment in progress, got %v", got)
+	}
+	if inProgress, ok := got[open.id]; !ok || !inProgress {
+		t.Errorf("want the open subsegment in progress, got %v", got)
+	}
+}
+
+func TestClient_Drain(t *testing.T) {
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+	client := ContextClient(ctx)
+
+	_, seg := BeginSegment(ctx, "foobar")
+	client.drain(time.Minute)
+
+	// the client is still open until the segment is closed.
+	seg.Close()
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != seg.id {
+		t.Errorf("want %s, got %s", seg.id, got.ID)
+	}
+
+	client.mu.Lock()
+	closed := client.closed
+	client.mu.Unlock()
+	if !closed {
+		t.Error("want closed, but not")
+	}
+}
+
+func TestClient_DrainTimeout(t *testing.T) {
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+	client := ContextClient(ctx)
+
+	// the segment is never closed.
+	_, seg := BeginSegment(ctx, "foobar")
+	client.drain(10 * time.Millisecond)
+
+	// the segment is flushed as in-progress, and the client is closed.
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != seg.id || !got.InProgress {
+		t.Errorf("want in-progress segment %s, got %s", seg.id, got.ID)
+	}
+
+	client.mu.Lock()
+	closed := client.closed
+	client.mu.Unlock()
+	if !closed {
+		t.Error("want closed, but not")
+	}
+
+	// the client doesn't hold the segments after it is closed.
+	BeginSegment(ctx, "leaked")
+	client.muSegments.Lock()
+	n := len(client.segments)
+	client.muSegments.Unlock()
+	if n != 0 {
+		t.Errorf("want no segments, got %d", n)
+	}
+}
+
+func TestClient_CloseOwnedStrategy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	before := runtime.NumGoroutine()
+	client := New(&Config{
+		DaemonAddress: strings.TrimPrefix(ts.URL, "http://"),
+	})
+	ctx := WithClient(context.Background(), client)
+	ctx = xraylog.WithLogger(ctx, xraylog.NullLogger{})
+	_, seg := BeginSegment(ctx, "foobar") // start the pollers of the sampling strategy
+	seg.Close()
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the pollers should be stopped.
+	ts.CloseClientConnections()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines are leaked: before %d, after %d", before, after)
+	}
+}
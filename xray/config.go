@@ -25,6 +25,22 @@ type Config struct {
 	StreamingStrategy      StreamingStrategy
 	SamplingStrategy       sampling.Strategy
 	ContextMissingStrategy ctxmissing.Strategy
+
+	// IDGenerator generates trace IDs and segment IDs.
+	// By default, the SDK uses the generator returned by NewCryptoIDGenerator.
+	IDGenerator IDGenerator
+
+	// MetricsRecorder records the metrics derived from the root segments.
+	// It is called when a root segment is closed.
+	MetricsRecorder MetricsRecorder
+
+	// RecordUnsampledMetrics makes the SDK record the segments that are not sampled by MetricsRecorder.
+	// The unsampled segments are never sent to the daemon.
+	RecordUnsampledMetrics bool
+
+	// Emitter sends the segment documents instead of the UDP connection to the daemon.
+	// The client doesn't close the emitter, the caller should close it after closing the client.
+	Emitter Emitter
 }
 
 type daemonEndpoints struct {
@@ -0,0 +1,54 @@
+package ctxmissing
+
+import (
+	"context"
+	"sync"
+)
+
+// CountingStrategy counts the context missing errors by the segment name,
+// and passes them to Next.
+type CountingStrategy struct {
+	// Next is the strategy called after counting.
+	// If it is nil, the errors are ignored.
+	Next Strategy
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// ContextMissing implements Strategy.
+func (s *CountingStrategy) ContextMissing(ctx context.Context, v interface{}) {
+	name := SegmentName(ctx)
+	s.mu.Lock()
+	if s.counts == nil {
+		s.counts = make(map[string]int64)
+	}
+	s.counts[name]++
+	s.mu.Unlock()
+
+	if s.Next != nil {
+		s.Next.ContextMissing(ctx, v)
+	}
+}
+
+// Counts returns the number of the context missing errors by the segment name.
+func (s *CountingStrategy) Counts() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make(map[string]int64, len(s.counts))
+	for name, count := range s.counts {
+		ret[name] = count
+	}
+	return ret
+}
+
+// Total returns the total number of the context missing errors.
+func (s *CountingStrategy) Total() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total int64
+	for _, count := range s.counts {
+		total += count
+	}
+	return total
+}
@@ -0,0 +1,43 @@
+package ctxmissing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ Strategy = (*CountingStrategy)(nil)
+
+func TestCounting(t *testing.T) {
+	var got []interface{}
+	strategy := &CountingStrategy{
+		Next: strategyFunc(func(ctx context.Context, v interface{}) {
+			got = append(got, v)
+		}),
+	}
+	ctx := context.Background()
+	strategy.ContextMissing(WithSegmentName(ctx, "foo"), "MISSING foo")
+	strategy.ContextMissing(WithSegmentName(ctx, "foo"), "MISSING foo")
+	strategy.ContextMissing(WithSegmentName(ctx, "bar"), "MISSING bar")
+
+	want := map[string]int64{
+		"foo": 2,
+		"bar": 1,
+	}
+	if diff := cmp.Diff(want, strategy.Counts()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if strategy.Total() != 3 {
+		t.Errorf("want %d, got %d", 3, strategy.Total())
+	}
+	if len(got) != 3 {
+		t.Errorf("want %d, got %d", 3, len(got))
+	}
+}
+
+type strategyFunc func(ctx context.Context, v interface{})
+
+func (f strategyFunc) ContextMissing(ctx context.Context, v interface{}) {
+	f(ctx, v)
+}
@@ -1,9 +1,58 @@
 package ctxmissing
 
-import "context"
+import (
+	"context"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
+)
 
 // Strategy provides an interface for
 // implementing context missing strategies.
 type Strategy interface {
 	ContextMissing(ctx context.Context, v interface{})
 }
+
+// contextKey is a value for use with context.WithValue. It's used as
+// a pointer so it fits in an interface{} without allocation.
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string { return "xray context value " + k.name }
+
+var segmentNameContextKey = &contextKey{"segment-name"}
+var traceIDContextKey = &contextKey{"trace-id"}
+
+// WithSegmentName returns a new context with the name of the segment whose context is missing.
+func WithSegmentName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, segmentNameContextKey, name)
+}
+
+// SegmentName returns the name of the segment whose context is missing.
+func SegmentName(ctx context.Context) string {
+	name, _ := ctx.Value(segmentNameContextKey).(string)
+	return name
+}
+
+// WithTraceID returns a new context with the trace ID of the segment whose context is missing.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey, traceID)
+}
+
+// TraceID returns the trace ID of the segment whose context is missing.
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDContextKey).(string)
+	return id
+}
+
+// logAttrs returns the attributes of the log for the missing context.
+func logAttrs(ctx context.Context) []xraylog.Attr {
+	var attrs []xraylog.Attr
+	if name := SegmentName(ctx); name != "" {
+		attrs = append(attrs, xraylog.String("segment_name", name))
+	}
+	if id := TraceID(ctx); id != "" {
+		attrs = append(attrs, xraylog.String("trace_id", id))
+	}
+	return attrs
+}
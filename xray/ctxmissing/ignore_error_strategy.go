@@ -0,0 +1,11 @@
+package ctxmissing
+
+import "context"
+
+// IgnoreErrorStrategy ignores the error when the segment context is missing.
+type IgnoreErrorStrategy struct{}
+
+// ContextMissing implements Strategy.
+func (*IgnoreErrorStrategy) ContextMissing(ctx context.Context, v interface{}) {
+	// do nothing
+}
@@ -0,0 +1,24 @@
+package ctxmissing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
+)
+
+var _ Strategy = (*IgnoreErrorStrategy)(nil)
+
+func TestIgnoreError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := xraylog.NewDefaultLogger(&buf, xraylog.LogLevelDebug)
+	ctx := xraylog.WithLogger(context.Background(), logger)
+
+	strategy := &IgnoreErrorStrategy{}
+	strategy.ContextMissing(ctx, "MISSING!!!")
+
+	if buf.Len() != 0 {
+		t.Errorf("unexpected log: %s", buf.String())
+	}
+}
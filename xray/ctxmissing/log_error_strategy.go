@@ -2,6 +2,7 @@ package ctxmissing
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
 )
@@ -11,5 +12,5 @@ type LogErrorStrategy struct{}
 
 // ContextMissing implements Strategy.
 func (*LogErrorStrategy) ContextMissing(ctx context.Context, v interface{}) {
-	xraylog.Errorf(ctx, "AWS X-Ray context missing: %v", v)
+	xraylog.ErrorAttrs(ctx, fmt.Sprintf("AWS X-Ray context missing: %v", v), logAttrs(ctx)...)
 }
@@ -3,6 +3,7 @@ package ctxmissing
 import (
 	"bytes"
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
@@ -22,3 +23,22 @@ func TestLogError(t *testing.T) {
 		t.Errorf("unexpected log: %s", buf.String())
 	}
 }
+
+func TestLogError_Attrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := xraylog.NewDefaultLogger(&buf, xraylog.LogLevelError)
+	ctx := xraylog.WithLogger(context.Background(), logger)
+	ctx = WithSegmentName(ctx, "foobar")
+	ctx = WithTraceID(ctx, "1-5e645f3e-1dfad076a177c5ccc5de12f5")
+
+	strategy := &LogErrorStrategy{}
+	strategy.ContextMissing(ctx, "MISSING!!!")
+
+	log := buf.String()
+	if !strings.Contains(log, "segment_name=foobar") || !strings.Contains(log, "trace_id=1-5e645f3e-1dfad076a177c5ccc5de12f5") {
+		t.Errorf("unexpected log: %s", log)
+	}
+	if strings.Count(log, "foobar") != 1 {
+		t.Errorf("the segment name is duplicated: %s", log)
+	}
+}
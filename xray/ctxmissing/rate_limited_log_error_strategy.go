@@ -0,0 +1,96 @@
+package ctxmissing
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
+)
+
+const defaultLogInterval = time.Minute
+
+// the prefix of the functions that are omitted from the captured stack.
+// it covers all packages of the SDK, e.g. xrayhttp, xraysql and xrayaws.
+const sdkPackagePrefix = "github.com/shogo82148/aws-xray-yasdk-go/"
+
+// RateLimitedLogErrorStrategy logs the error when the segment context is missing,
+// but it logs at most once per Interval.
+// The number of the suppressed errors is reported with the next log.
+type RateLimitedLogErrorStrategy struct {
+	// Interval is the minimum interval between logs.
+	// If it is zero, one minute is used.
+	Interval time.Duration
+
+	// CaptureStack adds the stack trace into the log
+	// to help finding the call site that has no segment context.
+	CaptureStack bool
+
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int64
+}
+
+// ContextMissing implements Strategy.
+func (s *RateLimitedLogErrorStrategy) ContextMissing(ctx context.Context, v interface{}) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultLogInterval
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	if !s.last.IsZero() && now.Sub(s.last) < interval {
+		s.suppressed++
+		s.mu.Unlock()
+		return
+	}
+	s.last = now
+	suppressed := s.suppressed
+	s.suppressed = 0
+	s.mu.Unlock()
+
+	msg := fmt.Sprintf("AWS X-Ray context missing: %v", v)
+	if suppressed > 0 {
+		msg = fmt.Sprintf("%s (%d similar errors suppressed)", msg, suppressed)
+	}
+	attrs := logAttrs(ctx)
+	if s.CaptureStack {
+		attrs = append(attrs, xraylog.String("stack", callers()))
+	}
+	xraylog.ErrorAttrs(ctx, msg, attrs...)
+}
+
+// callers returns the stack trace of the caller, without the frames of the SDK.
+func callers() string {
+	var pc [64]uintptr
+	n := runtime.Callers(3, pc[:])
+	frames := runtime.CallersFrames(pc[:n])
+
+	var builder strings.Builder
+	for {
+		frame, more := frames.Next()
+		if !isSDKFrame(frame) {
+			builder.WriteString(frame.Function)
+			builder.WriteString("\n\t")
+			builder.WriteString(frame.File)
+			builder.WriteString(":")
+			builder.WriteString(strconv.Itoa(frame.Line))
+			builder.WriteString("\n")
+		}
+		if !more {
+			break
+		}
+	}
+	return builder.String()
+}
+
+// isSDKFrame reports whether the frame is in the SDK.
+// The tests of the SDK are the users of the SDK.
+func isSDKFrame(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, sdkPackagePrefix) && !strings.HasSuffix(frame.File, "_test.go")
+}
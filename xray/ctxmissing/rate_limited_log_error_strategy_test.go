@@ -0,0 +1,101 @@
+package ctxmissing
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
+)
+
+var _ Strategy = (*RateLimitedLogErrorStrategy)(nil)
+
+func TestRateLimitedLogError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := xraylog.NewDefaultLogger(&buf, xraylog.LogLevelError)
+	ctx := xraylog.WithLogger(context.Background(), logger)
+
+	strategy := &RateLimitedLogErrorStrategy{
+		Interval: time.Hour,
+	}
+	strategy.ContextMissing(ctx, "MISSING1")
+	strategy.ContextMissing(ctx, "MISSING2")
+	strategy.ContextMissing(ctx, "MISSING3")
+
+	log := buf.String()
+	if !strings.Contains(log, "MISSING1") || strings.Contains(log, "suppressed") {
+		t.Errorf("unexpected log: %s", log)
+	}
+	if strings.Contains(log, "MISSING2") || strings.Contains(log, "MISSING3") {
+		t.Errorf("unexpected log: %s", log)
+	}
+
+	// the interval is passed.
+	buf.Reset()
+	strategy.last = time.Now().Add(-2 * time.Hour)
+	strategy.ContextMissing(ctx, "MISSING4")
+	log = buf.String()
+	if !strings.Contains(log, "MISSING4 (2 similar errors suppressed)") {
+		t.Errorf("unexpected log: %s", log)
+	}
+}
+
+func TestRateLimitedLogError_CaptureStack(t *testing.T) {
+	var buf bytes.Buffer
+	logger := xraylog.NewDefaultLogger(&buf, xraylog.LogLevelError)
+	ctx := xraylog.WithLogger(context.Background(), logger)
+
+	strategy := &RateLimitedLogErrorStrategy{
+		CaptureStack: true,
+	}
+	strategy.ContextMissing(ctx, "MISSING!!!")
+
+	log := buf.String()
+	if !strings.Contains(log, "TestRateLimitedLogError_CaptureStack") {
+		t.Errorf("the call site is not found: %s", log)
+	}
+}
+
+func TestIsSDKFrame(t *testing.T) {
+	tests := []struct {
+		frame runtime.Frame
+		want  bool
+	}{
+		{
+			frame: runtime.Frame{
+				Function: "github.com/shogo82148/aws-xray-yasdk-go/xray.BeginSubsegment",
+				File:     "/go/src/github.com/shogo82148/aws-xray-yasdk-go/xray/segment.go",
+			},
+			want: true,
+		},
+		{
+			frame: runtime.Frame{
+				Function: "github.com/shogo82148/aws-xray-yasdk-go/xrayhttp.(*roundtripper).RoundTrip",
+				File:     "/go/src/github.com/shogo82148/aws-xray-yasdk-go/xrayhttp/client.go",
+			},
+			want: true,
+		},
+		{
+			frame: runtime.Frame{
+				Function: "github.com/shogo82148/aws-xray-yasdk-go/xrayhttp.TestClient",
+				File:     "/go/src/github.com/shogo82148/aws-xray-yasdk-go/xrayhttp/client_test.go",
+			},
+			want: false,
+		},
+		{
+			frame: runtime.Frame{
+				Function: "main.main",
+				File:     "/go/src/example.com/app/main.go",
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		if got := isSDKFrame(tt.frame); got != tt.want {
+			t.Errorf("isSDKFrame(%s): want %t, got %t", tt.frame.Function, tt.want, got)
+		}
+	}
+}
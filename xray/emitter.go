@@ -0,0 +1,41 @@
+package xray
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
+)
+
+// Emitter sends the segment documents to a tracing backend instead of AWS X-Ray daemon.
+// See the github.com/shogo82148/aws-xray-yasdk-go/xray/zipkin package for an implementation.
+type Emitter interface {
+	// Emit sends the segment document.
+	// The doc may be in progress, or may be a subsegment that is streamed separately.
+	// The emitter must not modify nor retain the doc after Emit returns.
+	Emit(ctx context.Context, doc *schema.Segment) error
+}
+
+// DropCounter is implemented by the emitters that may drop the documents, e.g. when their queue is full.
+type DropCounter interface {
+	// Dropped returns the number of the dropped items, e.g. the spans of Zipkin.
+	Dropped() uint64
+}
+
+func (c *Client) emitToEmitter(ctx context.Context, seg *schema.Segment) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		xraylog.ErrorAttrs(ctx, "failed to emit: the client is already closed", xraylog.String("trace_id", seg.TraceID), xraylog.String("segment_id", seg.ID))
+		return
+	}
+
+	if err := c.emitter.Emit(ctx, seg); err != nil {
+		atomic.AddUint64(&c.stats.writeErrors, 1)
+		xraylog.ErrorAttrs(ctx, "failed to emit", xraylog.Err(err), xraylog.String("trace_id", seg.TraceID), xraylog.String("segment_id", seg.ID))
+		return
+	}
+	atomic.AddUint64(&c.stats.segmentsEmitted, 1)
+}
@@ -0,0 +1,156 @@
+package xray
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IDGenerator generates trace IDs and segment IDs.
+// It may be called concurrently from multiple goroutines.
+type IDGenerator interface {
+	// NewTraceID returns a new trace ID, e.g. "1-58406520-a006649127e371903a2de979".
+	NewTraceID() string
+
+	// NewSegmentID returns a new 64-bit segment ID in 16 hexadecimal digits.
+	NewSegmentID() string
+}
+
+var defaultIDGenerator = NewCryptoIDGenerator()
+
+type cryptoIDGenerator struct{}
+
+// NewCryptoIDGenerator returns a new IDGenerator that uses crypto/rand.
+// It is the default generator. It panics if crypto/rand fails.
+func NewCryptoIDGenerator() IDGenerator {
+	return cryptoIDGenerator{}
+}
+
+func (cryptoIDGenerator) NewTraceID() string {
+	var r [12]byte
+	_, err := crand.Read(r[:])
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("1-%08x-%x", nowFunc().Unix(), r)
+}
+
+func (cryptoIDGenerator) NewSegmentID() string {
+	var r [8]byte
+	_, err := crand.Read(r[:])
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x", r)
+}
+
+type fastIDGenerator struct {
+	pool sync.Pool
+}
+
+// NewFastIDGenerator returns a new IDGenerator that uses math/rand.
+// The generators are seeded by crypto/rand, and never block on the entropy source.
+// The IDs are not cryptographically secure.
+func NewFastIDGenerator() IDGenerator {
+	return &fastIDGenerator{
+		pool: sync.Pool{
+			New: func() interface{} {
+				var seed int64
+				if err := binary.Read(crand.Reader, binary.BigEndian, &seed); err != nil {
+					// fallback to timestamp
+					seed = time.Now().UnixNano()
+				}
+				return rand.New(rand.NewSource(seed))
+			},
+		},
+	}
+}
+
+func (g *fastIDGenerator) NewTraceID() string {
+	rnd := g.pool.Get().(*rand.Rand)
+	defer g.pool.Put(rnd)
+	var r [12]byte
+	binary.BigEndian.PutUint32(r[:4], rnd.Uint32())
+	binary.BigEndian.PutUint64(r[4:], rnd.Uint64())
+	return fmt.Sprintf("1-%08x-%x", nowFunc().Unix(), r)
+}
+
+func (g *fastIDGenerator) NewSegmentID() string {
+	rnd := g.pool.Get().(*rand.Rand)
+	defer g.pool.Put(rnd)
+	var r [8]byte
+	binary.BigEndian.PutUint64(r[:], rnd.Uint64())
+	return fmt.Sprintf("%x", r)
+}
+
+type deterministicIDGenerator struct {
+	mu    sync.Mutex
+	rnd   *rand.Rand
+	epoch int64
+}
+
+// NewDeterministicIDGenerator returns a new IDGenerator that generates the same sequence of IDs for the same seed.
+// The timestamp part of the trace IDs is always epoch.
+// It is intended for golden tests.
+func NewDeterministicIDGenerator(seed int64, epoch time.Time) IDGenerator {
+	return &deterministicIDGenerator{
+		rnd:   rand.New(rand.NewSource(seed)),
+		epoch: epoch.Unix(),
+	}
+}
+
+func (g *deterministicIDGenerator) NewTraceID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var r [12]byte
+	binary.BigEndian.PutUint32(r[:4], g.rnd.Uint32())
+	binary.BigEndian.PutUint64(r[4:], g.rnd.Uint64())
+	return fmt.Sprintf("1-%08x-%x", g.epoch, r)
+}
+
+func (g *deterministicIDGenerator) NewSegmentID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var r [8]byte
+	binary.BigEndian.PutUint64(r[:], g.rnd.Uint64())
+	return fmt.Sprintf("%x", r)
+}
+
+var errInvalidTraceID = errors.New("xray: invalid trace id")
+
+// TraceIDToW3C converts the X-Ray trace ID into the 128-bit trace ID of W3C Trace Context.
+// e.g. "1-58406520-a006649127e371903a2de979" is converted into "58406520a006649127e371903a2de979".
+// The segment IDs are compatible with the parent IDs of W3C Trace Context, so they need no conversion.
+func TraceIDToW3C(traceID string) (string, error) {
+	// "1-" + 8 hex digits + "-" + 24 hex digits
+	if len(traceID) != 35 || !strings.HasPrefix(traceID, "1-") || traceID[10] != '-' {
+		return "", errInvalidTraceID
+	}
+	id := traceID[2:10] + traceID[11:]
+	if !isHex(id) {
+		return "", errInvalidTraceID
+	}
+	return strings.ToLower(id), nil
+}
+
+// TraceIDFromW3C converts the 128-bit trace ID of W3C Trace Context into the X-Ray trace ID.
+// e.g. "58406520a006649127e371903a2de979" is converted into "1-58406520-a006649127e371903a2de979".
+// The first 32 bits of the W3C trace ID are used as the timestamp of the X-Ray trace ID.
+func TraceIDFromW3C(traceID string) (string, error) {
+	if len(traceID) != 32 || !isHex(traceID) {
+		return "", errInvalidTraceID
+	}
+	traceID = strings.ToLower(traceID)
+	return "1-" + traceID[:8] + "-" + traceID[8:], nil
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,145 @@
+package xray
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestIDGenerator(t *testing.T) {
+	traceIDPattern := regexp.MustCompile(`^1-[0-9a-f]{8}-[0-9a-f]{24}$`)
+	segmentIDPattern := regexp.MustCompile(`^[0-9a-f]{16}$`)
+	generators := map[string]IDGenerator{
+		"crypto":        NewCryptoIDGenerator(),
+		"fast":          NewFastIDGenerator(),
+		"deterministic": NewDeterministicIDGenerator(42, time.Unix(1000000000, 0)),
+	}
+	for name, g := range generators {
+		g := g
+		t.Run(name, func(t *testing.T) {
+			if id := g.NewTraceID(); !traceIDPattern.MatchString(id) {
+				t.Errorf("trace id should match %q, but got %q", traceIDPattern, id)
+			}
+			if id := g.NewSegmentID(); !segmentIDPattern.MatchString(id) {
+				t.Errorf("segment id should match %q, but got %q", segmentIDPattern, id)
+			}
+		})
+	}
+}
+
+func TestDeterministicIDGenerator(t *testing.T) {
+	epoch := time.Unix(1000000000, 0)
+	g1 := NewDeterministicIDGenerator(42, epoch)
+	g2 := NewDeterministicIDGenerator(42, epoch)
+	for i := 0; i < 10; i++ {
+		id1, id2 := g1.NewTraceID(), g2.NewTraceID()
+		if id1 != id2 {
+			t.Errorf("want %q, got %q", id1, id2)
+		}
+		if id1[:10] != "1-3b9aca00" {
+			t.Errorf("unexpected timestamp: %q", id1)
+		}
+		id1, id2 = g1.NewSegmentID(), g2.NewSegmentID()
+		if id1 != id2 {
+			t.Errorf("want %q, got %q", id1, id2)
+		}
+	}
+}
+
+func TestConfig_IDGenerator(t *testing.T) {
+	epoch := time.Unix(1000000000, 0)
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+	ctx = WithClient(ctx, New(&Config{
+		DaemonAddress:    "udp:" + td.conn.LocalAddr().String(),
+		SamplingStrategy: ContextClient(ctx).samplingStrategy,
+		IDGenerator:      NewDeterministicIDGenerator(42, epoch),
+	}))
+	want := NewDeterministicIDGenerator(42, epoch)
+
+	ctx, seg := BeginSegment(ctx, "foobar")
+	_, sub := BeginSubsegment(ctx, "subsegment")
+	if seg.traceID != want.NewTraceID() {
+		t.Errorf("unexpected trace id: %q", seg.traceID)
+	}
+	if seg.id != want.NewSegmentID() {
+		t.Errorf("unexpected segment id: %q", seg.id)
+	}
+	if sub.id != want.NewSegmentID() {
+		t.Errorf("unexpected segment id: %q", sub.id)
+	}
+	sub.Close()
+	seg.Close()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TraceID != seg.traceID {
+		t.Errorf("want %q, got %q", seg.traceID, got.TraceID)
+	}
+	ContextClient(ctx).Close()
+}
+
+func TestTraceIDToW3C(t *testing.T) {
+	got, err := TraceIDToW3C("1-58406520-A006649127E371903A2DE979")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "58406520a006649127e371903a2de979" {
+		t.Errorf("want %q, got %q", "58406520a006649127e371903a2de979", got)
+	}
+
+	invalid := []string{
+		"",
+		"58406520a006649127e371903a2de979",
+		"2-58406520-a006649127e371903a2de979",
+		"1-58406520-a006649127e371903a2de97z",
+		"1-58406520a-006649127e371903a2de979",
+	}
+	for _, id := range invalid {
+		if _, err := TraceIDToW3C(id); err == nil {
+			t.Errorf("%q: want error, got nil", id)
+		}
+	}
+}
+
+func TestTraceIDFromW3C(t *testing.T) {
+	got, err := TraceIDFromW3C("58406520a006649127e371903a2de979")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "1-58406520-a006649127e371903a2de979" {
+		t.Errorf("want %q, got %q", "1-58406520-a006649127e371903a2de979", got)
+	}
+
+	invalid := []string{
+		"",
+		"1-58406520-a006649127e371903a2de979",
+		"58406520a006649127e371903a2de97z",
+	}
+	for _, id := range invalid {
+		if _, err := TraceIDFromW3C(id); err == nil {
+			t.Errorf("%q: want error, got nil", id)
+		}
+	}
+}
+
+func BenchmarkIDGenerator(b *testing.B) {
+	b.Run("crypto", func(b *testing.B) {
+		g := NewCryptoIDGenerator()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				g.NewSegmentID()
+			}
+		})
+	})
+	b.Run("fast", func(b *testing.B) {
+		g := NewFastIDGenerator()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				g.NewSegmentID()
+			}
+		})
+	})
+}
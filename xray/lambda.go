@@ -38,18 +38,19 @@ func init() {
 	}
 }
 
-func beginSubsegmentForLambda(ctx context.Context, header, name string) (context.Context, *Segment) {
+func beginSubsegmentForLambda(ctx context.Context, header, name string, now time.Time) (context.Context, *Segment) {
 	h := ParseTraceHeader(header)
 	h.SamplingDecision = SamplingDecisionSampled
+	client := ContextClient(ctx)
 	if h.TraceID == "" {
-		h.TraceID = NewTraceID()
+		h.TraceID = client.idGenerator.NewTraceID()
 	}
 
 	seg := &Segment{
 		ctx:           ctx,
 		name:          sanitizeSegmentName(name),
-		id:            NewSegmentID(),
-		startTime:     nowFunc(),
+		id:            client.idGenerator.NewSegmentID(),
+		startTime:     now,
 		totalSegments: 1,
 		sampled:       true,
 		traceID:       h.TraceID,
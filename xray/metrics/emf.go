@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+const (
+	// the factor between the neighboring buckets of the histogram.
+	// the error of the latency is less than about 10%.
+	bucketFactor = 1.189207115002721 // 2^(1/4)
+
+	// the range of bucket indexes.
+	// CloudWatch accepts up to 100 distinct values per metric in a document.
+	// bucketFactor^minBucket is about 0.03 ms, and bucketFactor^maxBucket is about 15 minutes.
+	minBucket = -20
+	maxBucket = 79
+
+	// the maximum number of the values of a metric in an EMF document.
+	maxEMFValues = 100
+)
+
+// histogram is the distribution of latencies in milliseconds.
+type histogram struct {
+	counts map[int]int64
+}
+
+func (h *histogram) add(v float64) {
+	if h.counts == nil {
+		h.counts = make(map[int]int64)
+	}
+	idx := minBucket
+	if v > 0 {
+		idx = int(math.Round(math.Log(v) / math.Log(bucketFactor)))
+	}
+	if idx < minBucket {
+		idx = minBucket
+	}
+	if idx > maxBucket {
+		idx = maxBucket
+	}
+	h.counts[idx]++
+}
+
+// emf returns the latencies as lists of at most maxEMFValues numbers.
+// EMF accepts only a number or an array of numbers as the value of a metric,
+// so the value of each bucket is repeated by its count.
+func (h *histogram) emf() [][]float64 {
+	var ret [][]float64
+	var chunk []float64
+	for idx := minBucket; idx <= maxBucket; idx++ {
+		count := h.counts[idx]
+		if count == 0 {
+			continue
+		}
+		value := math.Pow(bucketFactor, float64(idx))
+		for i := int64(0); i < count; i++ {
+			chunk = append(chunk, value)
+			if len(chunk) == maxEMFValues {
+				ret = append(ret, chunk)
+				chunk = nil
+			}
+		}
+	}
+	if len(chunk) > 0 {
+		ret = append(ret, chunk)
+	}
+	return ret
+}
+
+type emfMetadata struct {
+	Timestamp         int64                 `json:"Timestamp"`
+	CloudWatchMetrics []emfMetricsDirective `json:"CloudWatchMetrics"`
+}
+
+type emfMetricsDirective struct {
+	Namespace  string                `json:"Namespace"`
+	Dimensions [][]string            `json:"Dimensions"`
+	Metrics    []emfMetricDefinition `json:"Metrics"`
+}
+
+type emfMetricDefinition struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+var emfMetricDefinitions = []emfMetricDefinition{
+	{Name: "Count", Unit: "Count"},
+	{Name: "Error", Unit: "Count"},
+	{Name: "Fault", Unit: "Count"},
+	{Name: "Throttle", Unit: "Count"},
+	{Name: "Latency", Unit: "Milliseconds"},
+}
+
+var emfLatencyDefinitions = []emfMetricDefinition{
+	{Name: "Latency", Unit: "Milliseconds"},
+}
+
+// encode encodes the metric into EMF documents terminated by a newline.
+// If the latencies don't fit in one document, the rest are written in the following documents that contain only the latencies.
+func (r *Recorder) encode(timestamp int64, key metricKey, v *metricValue) ([]byte, error) {
+	dimensionValues := map[string]string{}
+	dimensions := [][]string{{"ServiceName"}}
+	if len(r.cfg.dimensions) > 0 {
+		values := strings.Split(key.dimensions, "\x00")
+		for i, name := range r.cfg.dimensions {
+			if values[i] == "" {
+				// the annotation is not found.
+				continue
+			}
+			dimensionValues[name] = values[i]
+			dimensions = append(dimensions, []string{"ServiceName", name})
+		}
+	}
+
+	var buf []byte
+	latencies := v.latency.emf()
+	for i := 0; i == 0 || i < len(latencies); i++ {
+		doc := map[string]interface{}{
+			"ServiceName": key.name,
+		}
+		for name, value := range dimensionValues {
+			doc[name] = value
+		}
+		metrics := emfLatencyDefinitions
+		if i == 0 {
+			doc["Count"] = v.count
+			doc["Error"] = v.error
+			doc["Fault"] = v.fault
+			doc["Throttle"] = v.throttle
+			metrics = emfMetricDefinitions
+		}
+		if i < len(latencies) {
+			doc["Latency"] = latencies[i]
+		}
+		doc["_aws"] = emfMetadata{
+			Timestamp: timestamp,
+			CloudWatchMetrics: []emfMetricsDirective{
+				{
+					Namespace:  r.cfg.namespace,
+					Dimensions: dimensions,
+					Metrics:    metrics,
+				},
+			},
+		}
+
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, data...)
+		buf = append(buf, '\n')
+	}
+	return buf, nil
+}
@@ -0,0 +1,230 @@
+// Package metrics aggregates RED (Rate, Errors, Duration) metrics from the segments,
+// and writes them in CloudWatch Embedded Metric Format.
+//
+//	recorder := metrics.NewRecorder(os.Stdout, metrics.WithDimensions("route"))
+//	defer recorder.Close()
+//	xray.Configure(&xray.Config{
+//		MetricsRecorder:        recorder,
+//		RecordUnsampledMetrics: true,
+//	})
+//
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
+)
+
+const (
+	defaultNamespace = "aws-xray-yasdk-go"
+	defaultInterval  = time.Minute
+)
+
+var nowFunc func() time.Time = time.Now
+
+// Option is an option for NewRecorder.
+type Option func(*config)
+
+type config struct {
+	namespace  string
+	interval   time.Duration
+	dimensions []string
+}
+
+// WithNamespace configures the CloudWatch namespace of the metrics.
+// The default is "aws-xray-yasdk-go".
+func WithNamespace(namespace string) Option {
+	return func(cfg *config) {
+		cfg.namespace = namespace
+	}
+}
+
+// WithInterval configures the interval of flushing the metrics.
+// The default is one minute. If interval is zero or negative, the metrics are flushed only by Flush and Close.
+func WithInterval(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.interval = interval
+	}
+}
+
+// WithDimensions configures the annotation keys used as the dimensions of the metrics.
+// The metrics are aggregated per segment name, and also per segment name and each annotation.
+func WithDimensions(keys ...string) Option {
+	return func(cfg *config) {
+		cfg.dimensions = append(cfg.dimensions, keys...)
+	}
+}
+
+// Recorder aggregates the metrics from the segments.
+// It implements xray.MetricsRecorder.
+type Recorder struct {
+	w   io.Writer
+	cfg config
+
+	mu      sync.Mutex
+	metrics map[metricKey]*metricValue
+
+	muWrite   sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type metricKey struct {
+	name string
+
+	// the values of the annotations which is configured by WithDimensions.
+	// it is joined by "\x00" because arrays can't be used as map keys.
+	dimensions string
+}
+
+type metricValue struct {
+	count    int64
+	error    int64
+	fault    int64
+	throttle int64
+	latency  histogram
+}
+
+// NewRecorder returns a new recorder that writes the metrics into w.
+func NewRecorder(w io.Writer, opts ...Option) *Recorder {
+	cfg := config{
+		namespace: defaultNamespace,
+		interval:  defaultInterval,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Recorder{
+		w:       w,
+		cfg:     cfg,
+		metrics: make(map[metricKey]*metricValue),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	if cfg.interval > 0 {
+		go r.run(ctx)
+	} else {
+		close(r.done)
+	}
+	return r
+}
+
+// RecordSegment records the metrics of the root segment.
+// The in-progress segments are ignored.
+func (r *Recorder) RecordSegment(doc *schema.Segment) {
+	if doc == nil || doc.InProgress {
+		return
+	}
+	key := metricKey{
+		name:       doc.Name,
+		dimensions: r.dimensionValues(doc.Annotations),
+	}
+	latency := (doc.EndTime - doc.StartTime) * 1000 // in milliseconds
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.metrics[key]
+	if !ok {
+		v = &metricValue{}
+		r.metrics[key] = v
+	}
+	v.count++
+	if doc.Error {
+		v.error++
+	}
+	if doc.Fault {
+		v.fault++
+	}
+	if doc.Throttle {
+		v.throttle++
+	}
+	v.latency.add(latency)
+}
+
+func (r *Recorder) dimensionValues(annotations map[string]interface{}) string {
+	if len(r.cfg.dimensions) == 0 {
+		return ""
+	}
+	values := make([]string, len(r.cfg.dimensions))
+	for i, key := range r.cfg.dimensions {
+		if v, ok := annotations[key]; ok {
+			values[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(values, "\x00")
+}
+
+func (r *Recorder) run(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				xraylog.ErrorAttrs(ctx, "xray/metrics: failed to flush", xraylog.Err(err))
+			}
+		}
+	}
+}
+
+// Flush writes the aggregated metrics, and resets them.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	metrics := r.metrics
+	r.metrics = make(map[metricKey]*metricValue, len(metrics))
+	r.mu.Unlock()
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	keys := make([]metricKey, 0, len(metrics))
+	for key := range metrics {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].dimensions < keys[j].dimensions
+	})
+
+	timestamp := nowFunc().UnixNano() / int64(time.Millisecond)
+	r.muWrite.Lock()
+	defer r.muWrite.Unlock()
+	for _, key := range keys {
+		data, err := r.encode(timestamp, key, metrics[key])
+		if err != nil {
+			return err
+		}
+		if _, err := r.w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops flushing periodically, and flushes the remaining metrics.
+func (r *Recorder) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		r.cancel()
+		<-r.done
+		err = r.Flush()
+	})
+	return err
+}
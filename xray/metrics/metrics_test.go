@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+var _ xray.MetricsRecorder = (*Recorder)(nil)
+
+func fixedTime() time.Time {
+	return time.Unix(1000000000, 0)
+}
+
+func decode(t *testing.T, data string) []interface{} {
+	t.Helper()
+	var ret []interface{}
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		var v interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+func TestRecorder(t *testing.T) {
+	nowFunc = fixedTime
+	defer func() { nowFunc = time.Now }()
+
+	var buf bytes.Buffer
+	r := NewRecorder(&buf, WithInterval(0), WithNamespace("MyApp"), WithDimensions("route"))
+	r.RecordSegment(&schema.Segment{
+		Name:        "foobar",
+		StartTime:   1000000000,
+		EndTime:     1000000000.1,
+		Annotations: map[string]interface{}{"route": "/foo"},
+	})
+	r.RecordSegment(&schema.Segment{
+		Name:        "foobar",
+		StartTime:   1000000000,
+		EndTime:     1000000000.1,
+		Fault:       true,
+		Annotations: map[string]interface{}{"route": "/foo"},
+	})
+	r.RecordSegment(&schema.Segment{
+		Name:      "foobar",
+		StartTime: 1000000000,
+		EndTime:   1000000001,
+		Error:     true,
+		Throttle:  true,
+	})
+	r.RecordSegment(&schema.Segment{
+		Name:       "in-progress",
+		StartTime:  1000000000,
+		InProgress: true,
+	})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := decode(t, buf.String())
+	want := decode(t, `
+{"_aws":{"Timestamp":1000000000000,"CloudWatchMetrics":[{"Namespace":"MyApp","Dimensions":[["ServiceName"]],"Metrics":[{"Name":"Count","Unit":"Count"},{"Name":"Error","Unit":"Count"},{"Name":"Fault","Unit":"Count"},{"Name":"Throttle","Unit":"Count"},{"Name":"Latency","Unit":"Milliseconds"}]}]},"ServiceName":"foobar","Count":1,"Error":1,"Fault":0,"Throttle":1,"Latency":[1023.9999999999977]}
+{"_aws":{"Timestamp":1000000000000,"CloudWatchMetrics":[{"Namespace":"MyApp","Dimensions":[["ServiceName"],["ServiceName","route"]],"Metrics":[{"Name":"Count","Unit":"Count"},{"Name":"Error","Unit":"Count"},{"Name":"Fault","Unit":"Count"},{"Name":"Throttle","Unit":"Count"},{"Name":"Latency","Unit":"Milliseconds"}]}]},"ServiceName":"foobar","route":"/foo","Count":2,"Error":0,"Fault":1,"Throttle":0,"Latency":[107.6347411524753,107.6347411524753]}
+`)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecorder_FlushEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("want empty, got %q", buf.String())
+	}
+}
+
+func TestRecorder_SplitLatency(t *testing.T) {
+	nowFunc = fixedTime
+	defer func() { nowFunc = time.Now }()
+
+	var buf bytes.Buffer
+	r := NewRecorder(&buf, WithInterval(0))
+	for i := 0; i < maxEMFValues+1; i++ {
+		r.RecordSegment(&schema.Segment{
+			Name:      "foobar",
+			StartTime: 1000000000,
+			EndTime:   1000000001,
+		})
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := decode(t, buf.String())
+	if len(got) != 2 {
+		t.Fatalf("want 2 documents, got %d", len(got))
+	}
+	first := got[0].(map[string]interface{})
+	if first["Count"] != float64(maxEMFValues+1) {
+		t.Errorf("want %d, got %v", maxEMFValues+1, first["Count"])
+	}
+	if n := len(first["Latency"].([]interface{})); n != maxEMFValues {
+		t.Errorf("want %d, got %d", maxEMFValues, n)
+	}
+
+	// the second document contains only the rest of the latencies.
+	want := decode(t, `
+{"_aws":{"Timestamp":1000000000000,"CloudWatchMetrics":[{"Namespace":"aws-xray-yasdk-go","Dimensions":[["ServiceName"]],"Metrics":[{"Name":"Latency","Unit":"Milliseconds"}]}]},"ServiceName":"foobar","Latency":[1023.9999999999977]}
+`)
+	if diff := cmp.Diff(want[0], got[1]); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h histogram
+	h.add(0)
+	h.add(1)
+	h.add(1.05)
+	h.add(1e9)
+	got := h.emf()
+	if len(got) != 1 {
+		t.Fatalf("unexpected chunks: %v", got)
+	}
+	if len(got[0]) != 4 {
+		t.Fatalf("unexpected values: %v", got[0])
+	}
+	if got[0][1] != 1 || got[0][2] != 1 {
+		t.Errorf("want 1, got %v", got[0])
+	}
+}
@@ -0,0 +1,47 @@
+package xray
+
+import (
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// MetricsRecorder records the metrics derived from the root segments.
+// See the github.com/shogo82148/aws-xray-yasdk-go/xray/metrics package for an implementation.
+type MetricsRecorder interface {
+	// RecordSegment is called when a root segment is closed.
+	// The doc contains the attributes of the root segment, but it doesn't contain its subsegments.
+	// The recorder must not modify nor retain the doc.
+	RecordSegment(doc *schema.Segment)
+}
+
+func (c *Client) recordMetrics(seg *Segment) {
+	if c.metricsRecorder == nil {
+		return
+	}
+	c.metricsRecorder.RecordSegment(metricsDocument(seg))
+}
+
+// metricsDocument returns the summary of the root segment.
+func metricsDocument(seg *Segment) *schema.Segment {
+	seg.mu.RLock()
+	defer seg.mu.RUnlock()
+	startEpoch := float64(seg.startTime.Unix()) + float64(seg.startTime.Nanosecond())/1e9
+	ret := &schema.Segment{
+		Name:        seg.name,
+		ID:          seg.id,
+		TraceID:     seg.traceID,
+		StartTime:   startEpoch,
+		Error:       seg.error,
+		Throttle:    seg.throttle,
+		Fault:       seg.fault,
+		Origin:      seg.origin,
+		Annotations: seg.annotations,
+		HTTP:        seg.http,
+	}
+	if seg.inProgress() {
+		ret.InProgress = true
+	} else {
+		// use monotonic clock instead of wall clock to get correct proccessing time.
+		ret.EndTime = startEpoch + seg.endTime.Sub(seg.startTime).Seconds()
+	}
+	return ret
+}
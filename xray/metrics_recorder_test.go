@@ -0,0 +1,72 @@
+package xray
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+type testMetricsRecorder struct {
+	mu   sync.Mutex
+	docs []*schema.Segment
+}
+
+func (r *testMetricsRecorder) RecordSegment(doc *schema.Segment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, doc)
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	for _, recordUnsampled := range []bool{false, true} {
+		recorder := &testMetricsRecorder{}
+		client := New(&Config{
+			DaemonAddress:          "127.0.0.1:0",
+			SamplingStrategy:       neverSampleStrategy{},
+			MetricsRecorder:        recorder,
+			RecordUnsampledMetrics: recordUnsampled,
+		})
+		ctx := WithClient(context.Background(), client)
+
+		ctx, seg := BeginSegment(ctx, "foobar")
+		_, sub := BeginSubsegment(ctx, "sub")
+		sub.Close()
+		if recordUnsampled && len(seg.subsegments) != 0 {
+			t.Errorf("want the unsampled subsegments are not added, got %d", len(seg.subsegments))
+		}
+		seg.SetFault()
+		if h := DownstreamHeader(ctx); h.SamplingDecision != SamplingDecisionNotSampled {
+			t.Errorf("want %v, got %v", SamplingDecisionNotSampled, h.SamplingDecision)
+		}
+		seg.Close()
+		client.Close()
+
+		if !recordUnsampled {
+			if len(recorder.docs) != 0 {
+				t.Errorf("want no documents, got %d", len(recorder.docs))
+			}
+			continue
+		}
+		if len(recorder.docs) != 1 {
+			t.Fatalf("want 1 document, got %d", len(recorder.docs))
+		}
+		doc := recorder.docs[0]
+		if doc.Name != "foobar" {
+			t.Errorf("want %q, got %q", "foobar", doc.Name)
+		}
+		if !doc.Fault {
+			t.Error("want fault, but not")
+		}
+		if doc.InProgress || doc.EndTime < doc.StartTime {
+			t.Errorf("invalid time: start %f, end %f", doc.StartTime, doc.EndTime)
+		}
+		if len(doc.Subsegments) != 0 {
+			t.Errorf("want no subsegments, got %d", len(doc.Subsegments))
+		}
+		if got := client.Stats().SegmentsEmitted; got != 0 {
+			t.Errorf("want no segments are emitted, got %d", got)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
@@ -169,6 +170,10 @@ type unprocessedStatistics struct {
 
 // CentralizedStrategy is an implementation of SamplingStrategy.
 type CentralizedStrategy struct {
+	// the number of failures of refreshing.
+	// it is placed at the first of the struct to make sure 64-bit alignment for atomic operations.
+	refreshFailures uint64
+
 	// Sampling strategy used if centralized manifest is expired
 	fallback *LocalizedStrategy
 
@@ -183,6 +188,9 @@ type CentralizedStrategy struct {
 	pollerCancel context.CancelFunc
 	startOnce    sync.Once
 	muRefresh    sync.Mutex
+	muPoller     sync.Mutex
+	closed       bool
+	wg           sync.WaitGroup
 
 	mu       sync.RWMutex
 	manifest *centralizedManifest
@@ -295,9 +303,14 @@ func (s *CentralizedStrategy) getSamplingTargets(ctx context.Context, input *get
 	return &output, nil
 }
 
-// Close stops polling.
+// Close stops polling, and waits for the pollers to exit.
 func (s *CentralizedStrategy) Close() {
+	s.muPoller.Lock()
+	s.closed = true
 	s.pollerCancel()
+	s.muPoller.Unlock()
+
+	s.wg.Wait()
 }
 
 // ShouldTrace implements Strategy.
@@ -320,6 +333,11 @@ func (s *CentralizedStrategy) ShouldTrace(req *Request) *Decision {
 	return s.fallback.ShouldTrace(req)
 }
 
+// RefreshFailures returns the number of failures of refreshing the sampling rules and quotas.
+func (s *CentralizedStrategy) RefreshFailures() uint64 {
+	return atomic.LoadUint64(&s.refreshFailures)
+}
+
 func (s *CentralizedStrategy) getManifest() *centralizedManifest {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -334,11 +352,18 @@ func (s *CentralizedStrategy) setManifest(manifest *centralizedManifest) {
 
 // start should be called by `s.startOnce.Do(s.start)``
 func (s *CentralizedStrategy) start() {
+	s.muPoller.Lock()
+	defer s.muPoller.Unlock()
+	if s.closed {
+		return
+	}
+	s.wg.Add(2)
 	go s.rulePoller()
 	go s.quotaPoller()
 }
 
 func (s *CentralizedStrategy) rulePoller() {
+	defer s.wg.Done()
 	var seed int64
 	if err := binary.Read(crand.Reader, binary.BigEndian, &seed); err != nil {
 		// fallback to timestamp
@@ -362,6 +387,7 @@ func (s *CentralizedStrategy) rulePoller() {
 }
 
 func (s *CentralizedStrategy) quotaPoller() {
+	defer s.wg.Done()
 	var seed int64
 	if err := binary.Read(crand.Reader, binary.BigEndian, &seed); err != nil {
 		// fallback to timestamp
@@ -392,7 +418,8 @@ func (s *CentralizedStrategy) refreshRule() {
 	defer func() {
 		// avoid propagating panics to the application code.
 		if e := recover(); e != nil {
-			xraylog.Errorf(ctx, "panic: %v", e)
+			atomic.AddUint64(&s.refreshFailures, 1)
+			xraylog.ErrorAttrs(ctx, "xray/sampling: panic", xraylog.Any("panic", fmt.Sprint(e)))
 		}
 	}()
 
@@ -424,17 +451,24 @@ func (s *CentralizedStrategy) refreshRule() {
 			}
 			rules = append(rules, rule)
 			quotas[name] = quota
-			xraylog.Debugf(
-				ctx,
-				"Refresh Sampling Rule: Priority: %d, ServiceName: %s, ServiceType: %s, Name: %s, Host: %s, URL: %s, Method: %s, Quota: %d, FixedRate: %f",
-				r.Priority, r.ServiceName, r.ServiceType,
-				name, r.Host, r.HTTPMethod, r.URLPath, quota.quota, r.FixedRate,
+			xraylog.DebugAttrs(
+				ctx, "Refresh Sampling Rule",
+				xraylog.Int64("priority", r.Priority),
+				xraylog.String("service_name", r.ServiceName),
+				xraylog.String("service_type", r.ServiceType),
+				xraylog.String("rule_name", name),
+				xraylog.String("host", r.Host),
+				xraylog.String("url_path", r.URLPath),
+				xraylog.String("http_method", r.HTTPMethod),
+				xraylog.Int64("quota", quota.quota),
+				xraylog.Float64("fixed_rate", r.FixedRate),
 			)
 		}
 		return true
 	})
 	if err != nil {
-		xraylog.Errorf(ctx, "xray/sampling: failed to get sampling rules: %v", err)
+		atomic.AddUint64(&s.refreshFailures, 1)
+		xraylog.ErrorAttrs(ctx, "xray/sampling: failed to get sampling rules", xraylog.Err(err), xraylog.String("address", s.addr))
 		return
 	}
 	sort.Stable(centralizedRuleSlice(rules))
@@ -458,7 +492,8 @@ func (s *CentralizedStrategy) refreshQuota() {
 	defer func() {
 		// avoid propagating panics to the application code.
 		if e := recover(); e != nil {
-			xraylog.Errorf(ctx, "panic: %v", e)
+			atomic.AddUint64(&s.refreshFailures, 1)
+			xraylog.ErrorAttrs(ctx, "xray/sampling: panic", xraylog.Any("panic", fmt.Sprint(e)))
 		}
 	}()
 
@@ -475,9 +510,12 @@ func (s *CentralizedStrategy) refreshQuota() {
 			BorrowCount:  stat.borrowed,
 			Timestamp:    now.Format(time.RFC3339),
 		})
-		xraylog.Debugf(
-			ctx,
-			"Sampling Statistics: Name: %s, Requests: %d, Borrowed: %d, Sampled: %d", r.ruleName, stat.requests, stat.borrowed, stat.sampled,
+		xraylog.DebugAttrs(
+			ctx, "Sampling Statistics",
+			xraylog.String("rule_name", r.ruleName),
+			xraylog.Int64("requests", stat.requests),
+			xraylog.Int64("borrowed", stat.borrowed),
+			xraylog.Int64("sampled", stat.sampled),
 		)
 	}
 
@@ -492,23 +530,29 @@ func (s *CentralizedStrategy) refreshQuota() {
 		})
 		stats = stats[l:]
 		if err != nil {
-			xraylog.Errorf(ctx, "failed to refresh sampling targets: %v", err)
+			atomic.AddUint64(&s.refreshFailures, 1)
+			xraylog.ErrorAttrs(ctx, "xray/sampling: failed to refresh sampling targets", xraylog.Err(err), xraylog.String("address", s.addr))
 			continue
 		}
 		for _, doc := range resp.SamplingTargetDocuments {
 			if quota, ok := manifest.Quotas[doc.RuleName]; ok {
 				if err := quota.update(doc); err != nil {
-					xraylog.Errorf(
-						ctx,
-						"Failed to Refresh Quota: Name: %s, Quota: %d, TTL: %s, Interval: %d, Error: %v",
-						doc.RuleName, doc.ReservoirQuota, doc.ReservoirQuotaTTL, doc.Interval, err,
+					xraylog.ErrorAttrs(
+						ctx, "xray/sampling: failed to refresh quota",
+						xraylog.Err(err),
+						xraylog.String("rule_name", doc.RuleName),
+						xraylog.Int64("quota", doc.ReservoirQuota),
+						xraylog.String("ttl", doc.ReservoirQuotaTTL),
+						xraylog.Int64("interval", doc.Interval),
 					)
 					continue
 				}
-				xraylog.Debugf(
-					ctx,
-					"Refresh Quota: Name: %s, Quota: %d, TTL: %s, Interval: %d",
-					doc.RuleName, doc.ReservoirQuota, doc.ReservoirQuotaTTL, doc.Interval,
+				xraylog.DebugAttrs(
+					ctx, "Refresh Quota",
+					xraylog.String("rule_name", doc.RuleName),
+					xraylog.Int64("quota", doc.ReservoirQuota),
+					xraylog.String("ttl", doc.ReservoirQuotaTTL),
+					xraylog.Int64("interval", doc.Interval),
 				)
 			} else {
 				// new rule may be added? try to refresh.
@@ -526,6 +570,11 @@ func (s *CentralizedStrategy) refreshQuota() {
 
 	if needRefresh {
 		xraylog.Debug(ctx, "chaning sampling rules is detected. refresh them.")
-		go s.refreshRule()
+		// it is called from the quota poller, so s.wg is greater than zero here.
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.refreshRule()
+		}()
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 )
 
 var _ Strategy = (*CentralizedStrategy)(nil)
+var _ RefreshFailureCounter = (*CentralizedStrategy)(nil)
 
 func TestCentralizedStrategy_refreshRule(t *testing.T) {
 	chRules := make(chan *getSamplingRulesOutput, 1) // rules that return from X-Ray daemon
@@ -218,3 +220,31 @@ func TestCentralizedStrategy_refreshQuota(t *testing.T) {
 		t.Errorf("unexpected ttl: want %d, got %d", 1000000000, quota.ttl.Unix())
 	}
 }
+
+func TestCentralizedStrategy_RefreshFailures(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewCentralizedStrategy(u.Host, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.manifest.Rules = []*centralizedRule{
+		{
+			ruleName: "FooBar",
+			quota:    &centralizedQuota{},
+		},
+	}
+	s.refreshRule()
+	s.refreshQuota()
+
+	if got := s.RefreshFailures(); got != 2 {
+		t.Errorf("want %d, got %d", 2, got)
+	}
+}
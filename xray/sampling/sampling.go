@@ -19,3 +19,9 @@ type Request struct {
 type Strategy interface {
 	ShouldTrace(request *Request) *Decision
 }
+
+// RefreshFailureCounter is implemented by the strategies that refresh the sampling rules from remote.
+type RefreshFailureCounter interface {
+	// RefreshFailures returns the number of failures of refreshing the sampling rules and quotas.
+	RefreshFailures() uint64
+}
@@ -3,6 +3,7 @@
 package schema
 
 import (
+	"encoding/json"
 	"strings"
 )
 
@@ -175,47 +176,138 @@ func (aws AWS) SetAccountID(accountID string) {
 	aws["account_id"] = accountID
 }
 
+// ECS returns ECS. It also works with the documents decoded by encoding/json.
+// It returns nil if the information is not available.
+func (aws AWS) ECS() *ECS {
+	if v, ok := aws["ecs"].(*ECS); ok {
+		return v
+	}
+	var v ECS
+	if !aws.decode("ecs", &v) {
+		return nil
+	}
+	return &v
+}
+
 // SetECS sets ECS.
 func (aws AWS) SetECS(ecs *ECS) {
 	aws["ecs"] = ecs
 }
 
+// EKS returns EKS. It also works with the documents decoded by encoding/json.
+// It returns nil if the information is not available.
+func (aws AWS) EKS() *EKS {
+	if v, ok := aws["eks"].(*EKS); ok {
+		return v
+	}
+	var v EKS
+	if !aws.decode("eks", &v) {
+		return nil
+	}
+	return &v
+}
+
 // SetEKS sets EKS.
 func (aws AWS) SetEKS(eks *EKS) {
 	aws["eks"] = eks
 }
 
+// EC2 returns EC2. It also works with the documents decoded by encoding/json.
+// It returns nil if the information is not available.
+func (aws AWS) EC2() *EC2 {
+	if v, ok := aws["ec2"].(*EC2); ok {
+		return v
+	}
+	var v EC2
+	if !aws.decode("ec2", &v) {
+		return nil
+	}
+	return &v
+}
+
 // SetEC2 sets EC2.
 func (aws AWS) SetEC2(ec2 *EC2) {
 	aws["ec2"] = ec2
 }
 
+// ElasticBeanstalk returns ElasticBeanstalk. It also works with the documents decoded by encoding/json.
+// It returns nil if the information is not available.
+func (aws AWS) ElasticBeanstalk() *ElasticBeanstalk {
+	if v, ok := aws["elastic_beanstalk"].(*ElasticBeanstalk); ok {
+		return v
+	}
+	var v ElasticBeanstalk
+	if !aws.decode("elastic_beanstalk", &v) {
+		return nil
+	}
+	return &v
+}
+
 // SetElasticBeanstalk sets ElasticBeanstalk.
 func (aws AWS) SetElasticBeanstalk(bean *ElasticBeanstalk) {
 	aws["elastic_beanstalk"] = bean
 }
 
+// XRay returns XRay. It also works with the documents decoded by encoding/json.
+// It returns nil if the information is not available.
+func (aws AWS) XRay() *XRay {
+	if v, ok := aws["xray"].(*XRay); ok {
+		return v
+	}
+	var v XRay
+	if !aws.decode("xray", &v) {
+		return nil
+	}
+	return &v
+}
+
 // SetXRay sets XRay.
 func (aws AWS) SetXRay(xray *XRay) {
 	aws["xray"] = xray
 }
 
+// LogReferences returns the information about Amazon CloudWatch Logs.
+// It also works with the documents decoded by encoding/json.
+func (aws AWS) LogReferences() []*LogReference {
+	if v, ok := aws["cloudwatch_logs"].([]*LogReference); ok {
+		return v
+	}
+	var v []*LogReference
+	if !aws.decode("cloudwatch_logs", &v) {
+		return nil
+	}
+	return v
+}
+
 // AddLogReferences adds information about Amazon CloudWatch Logs.
 func (aws AWS) AddLogReferences(logs []*LogReference) {
 	if len(logs) == 0 {
 		return
 	}
-	if info, ok := aws["cloudwatch_logs"]; ok {
-		current := info.([]*LogReference)
-		current = append(current, logs...)
-		aws["cloudwatch_logs"] = current
-		return
-	}
-	clone := make([]*LogReference, len(logs))
-	copy(clone, logs)
+	current := aws.LogReferences()
+	clone := make([]*LogReference, 0, len(current)+len(logs))
+	clone = append(clone, current...)
+	clone = append(clone, logs...)
 	aws["cloudwatch_logs"] = clone
 }
 
+// decode decodes the value named the key into v.
+// The value may be a generic value decoded by encoding/json, e.g. map[string]interface{}.
+func (aws AWS) decode(key string, v interface{}) bool {
+	if aws == nil {
+		return false
+	}
+	value, ok := aws[key]
+	if !ok || value == nil {
+		return false
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
 // ECS is information about an Amazon ECS container.
 type ECS struct {
 	// The container ID of the container running your application.
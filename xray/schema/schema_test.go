@@ -1,6 +1,11 @@
 package schema
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
 
 func TestToSnakeCase(t *testing.T) {
 	tc := []struct {
@@ -19,3 +24,52 @@ func TestToSnakeCase(t *testing.T) {
 		}
 	}
 }
+
+func TestAWS_RoundTrip(t *testing.T) {
+	aws := AWS{}
+	aws.SetECS(&ECS{Container: "container", ContainerID: "container-id"})
+	aws.SetEKS(&EKS{ClusterName: "cluster", Pod: "pod", ContainerID: "container-id"})
+	aws.SetEC2(&EC2{InstanceID: "i-1234567890abcdef0", AvailabilityZone: "ap-northeast-1a"})
+	aws.SetElasticBeanstalk(&ElasticBeanstalk{EnvironmentName: "env", VersionLabel: "v1", DeploymentID: 42})
+	aws.SetXRay(&XRay{SDK: "X-Ray for Go", SDKVersion: "1.0.0"})
+	aws.AddLogReferences([]*LogReference{{LogGroup: "group1"}})
+
+	data, err := json.Marshal(aws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AWS
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(aws.ECS(), decoded.ECS()); diff != "" {
+		t.Errorf("ECS mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(aws.EKS(), decoded.EKS()); diff != "" {
+		t.Errorf("EKS mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(aws.EC2(), decoded.EC2()); diff != "" {
+		t.Errorf("EC2 mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(aws.ElasticBeanstalk(), decoded.ElasticBeanstalk()); diff != "" {
+		t.Errorf("ElasticBeanstalk mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(aws.XRay(), decoded.XRay()); diff != "" {
+		t.Errorf("XRay mismatch (-want +got):\n%s", diff)
+	}
+
+	// AddLogReferences doesn't panic with the decoded documents.
+	decoded.AddLogReferences([]*LogReference{{LogGroup: "group2"}})
+	want := []*LogReference{{LogGroup: "group1"}, {LogGroup: "group2"}}
+	if diff := cmp.Diff(want, decoded.LogReferences()); diff != "" {
+		t.Errorf("LogReferences mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAWS_Missing(t *testing.T) {
+	var aws AWS
+	if aws.ECS() != nil || aws.EKS() != nil || aws.EC2() != nil || aws.ElasticBeanstalk() != nil || aws.XRay() != nil || aws.LogReferences() != nil {
+		t.Error("want nil")
+	}
+}
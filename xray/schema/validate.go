@@ -0,0 +1,192 @@
+package schema
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidationError is a violation of the rules of the segment documents.
+type ValidationError struct {
+	// Path is the path to the invalid field, e.g. "subsegments[0].id".
+	Path string
+
+	// Message describes the violation.
+	Message string
+}
+
+func (err *ValidationError) Error() string {
+	return "schema: " + err.Path + ": " + err.Message
+}
+
+// ValidationErrors is a list of ValidationError returned by Validate.
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks that the segment document follows the rules of AWS X-Ray.
+// It checks the IDs, the trace ID format, the names, the time ordering,
+// the required fields of subsegments and the types of annotations.
+// If seg is a subsegment sent separately, its Type must be "subsegment".
+// The returned error is ValidationErrors if the document is invalid.
+func Validate(seg *Segment) error {
+	if seg == nil {
+		return ValidationErrors{{Path: "segment", Message: "segment is nil"}}
+	}
+	v := &validator{}
+	v.validateTopLevel(seg)
+	v.validateSegment("", seg)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) errorf(path, field, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{
+		Path:    joinPath(path, field),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateTopLevel checks the fields required only for the documents sent to the daemon.
+func (v *validator) validateTopLevel(seg *Segment) {
+	if seg.TraceID == "" {
+		v.errorf("", "trace_id", "trace_id is required")
+	} else if !isValidTraceID(seg.TraceID) {
+		v.errorf("", "trace_id", "invalid trace id: %q", seg.TraceID)
+	}
+	switch seg.Type {
+	case "":
+	case "subsegment":
+		if seg.ParentID == "" {
+			v.errorf("", "parent_id", "parent_id is required for subsegments sent separately")
+		}
+	default:
+		v.errorf("", "type", "unknown type: %q", seg.Type)
+	}
+}
+
+func (v *validator) validateSegment(path string, seg *Segment) {
+	if seg.Name == "" {
+		v.errorf(path, "name", "name is required")
+	} else if !isValidName(seg.Name) {
+		v.errorf(path, "name", "invalid name: %q", seg.Name)
+	}
+
+	if seg.ID == "" {
+		v.errorf(path, "id", "id is required")
+	} else if !isValidID(seg.ID) {
+		v.errorf(path, "id", "invalid id: %q", seg.ID)
+	}
+	if seg.ParentID != "" && !isValidID(seg.ParentID) {
+		v.errorf(path, "parent_id", "invalid id: %q", seg.ParentID)
+	}
+	for i, id := range seg.PrecursorIDs {
+		if !isValidID(id) {
+			v.errorf(path, fmt.Sprintf("precursor_ids[%d]", i), "invalid id: %q", id)
+		}
+	}
+
+	if seg.StartTime <= 0 {
+		v.errorf(path, "start_time", "start_time is required")
+	}
+	switch {
+	case seg.InProgress && seg.EndTime != 0:
+		v.errorf(path, "end_time", "end_time and in_progress must not be set at the same time")
+	case !seg.InProgress && seg.EndTime == 0:
+		v.errorf(path, "end_time", "end_time or in_progress is required")
+	case !seg.InProgress && seg.EndTime < seg.StartTime:
+		v.errorf(path, "end_time", "end_time %f is before start_time %f", seg.EndTime, seg.StartTime)
+	}
+
+	for key, value := range seg.Annotations {
+		if !isValidAnnotation(value) {
+			v.errorf(path, "annotations."+key, "annotation must be a string, a number or a boolean, got %T", value)
+		}
+	}
+
+	if seg.Cause != nil {
+		for i, e := range seg.Cause.Exceptions {
+			if e.ID != "" && !isValidID(e.ID) {
+				v.errorf(path, fmt.Sprintf("cause.exceptions[%d].id", i), "invalid id: %q", e.ID)
+			}
+		}
+	}
+
+	for i, sub := range seg.Subsegments {
+		if sub == nil {
+			v.errorf(path, fmt.Sprintf("subsegments[%d]", i), "subsegment is nil")
+			continue
+		}
+		v.validateSegment(joinPath(path, fmt.Sprintf("subsegments[%d]", i)), sub)
+	}
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// isValidID reports whether id is 64-bit identifier in 16 hexadecimal digits.
+func isValidID(id string) bool {
+	return len(id) == 16 && isHex(id)
+}
+
+// isValidTraceID reports whether id is in the trace ID format,
+// e.g. "1-58406520-a006649127e371903a2de979".
+func isValidTraceID(id string) bool {
+	// "1-" + 8 hex digits + "-" + 24 hex digits
+	if len(id) != 35 || !strings.HasPrefix(id, "1-") || id[10] != '-' {
+		return false
+	}
+	return isHex(id[2:10]) && isHex(id[11:])
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// name should match /\A[\p{L}\p{N}\p{Z}_.:\/%&#=+\-@]{1,200}\z/
+func isValidName(name string) bool {
+	if utf8.RuneCountInString(name) > 200 {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Z, r) {
+			continue
+		}
+		if r <= unicode.MaxASCII && strings.IndexByte("_.:/%&#=+-@", byte(r)) >= 0 {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func isValidAnnotation(value interface{}) bool {
+	switch value.(type) {
+	case string, bool, json.Number,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func validSegment() *Segment {
+	return &Segment{
+		Name:      "foobar",
+		ID:        "70de5b6f19ff9a0a",
+		TraceID:   "1-5759e988-bd862e3fe1be46a994272793",
+		StartTime: 1000,
+		EndTime:   1001,
+		Annotations: map[string]interface{}{
+			"string": "value",
+			"int":    42,
+			"float":  1.5,
+			"bool":   true,
+		},
+		Subsegments: []*Segment{
+			{
+				Name:         "child",
+				ID:           "70de5b6f19ff9a0b",
+				StartTime:    1000.5,
+				InProgress:   true,
+				PrecursorIDs: []string{"70de5b6f19ff9a0c"},
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(validSegment()); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+
+	// the names may contain the space separators \p{Z}.
+	seg := validSegment()
+	seg.Name = "a b\u3000c"
+	if err := Validate(seg); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+
+	// the segment decoded by encoding/json is also valid.
+	data, err := json.Marshal(validSegment())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded *Segment
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(decoded); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+
+	// a subsegment sent separately.
+	sub := &Segment{
+		Name:       "child",
+		ID:         "70de5b6f19ff9a0b",
+		TraceID:    "1-5759e988-bd862e3fe1be46a994272793",
+		ParentID:   "70de5b6f19ff9a0a",
+		Type:       "subsegment",
+		StartTime:  1000,
+		InProgress: true,
+	}
+	if err := Validate(sub); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+}
+
+func TestValidate_Invalid(t *testing.T) {
+	tc := []struct {
+		name   string
+		modify func(seg *Segment)
+		want   []string
+	}{
+		{
+			name: "invalid id",
+			modify: func(seg *Segment) {
+				seg.ID = "foobar"
+				seg.Subsegments[0].ID = ""
+			},
+			want: []string{"id", "subsegments[0].id"},
+		},
+		{
+			name: "invalid trace id",
+			modify: func(seg *Segment) {
+				seg.TraceID = "1-5759e988-bd862e3fe1be46a99427279z"
+			},
+			want: []string{"trace_id"},
+		},
+		{
+			name: "missing trace id",
+			modify: func(seg *Segment) {
+				seg.TraceID = ""
+			},
+			want: []string{"trace_id"},
+		},
+		{
+			name: "invalid name",
+			modify: func(seg *Segment) {
+				seg.Name = "foo!bar"
+				seg.Subsegments[0].Name = strings.Repeat("a", 201)
+			},
+			want: []string{"name", "subsegments[0].name"},
+		},
+		{
+			name: "backslash in name",
+			modify: func(seg *Segment) {
+				seg.Name = `a\b`
+			},
+			want: []string{"name"},
+		},
+		{
+			name: "tab in name",
+			modify: func(seg *Segment) {
+				seg.Name = "a\tb"
+			},
+			want: []string{"name"},
+		},
+		{
+			name: "end time before start time",
+			modify: func(seg *Segment) {
+				seg.EndTime = 999
+			},
+			want: []string{"end_time"},
+		},
+		{
+			name: "missing end time",
+			modify: func(seg *Segment) {
+				seg.Subsegments[0].InProgress = false
+			},
+			want: []string{"subsegments[0].end_time"},
+		},
+		{
+			name: "missing start time",
+			modify: func(seg *Segment) {
+				seg.Subsegments[0].StartTime = 0
+			},
+			want: []string{"subsegments[0].start_time"},
+		},
+		{
+			name: "invalid annotation",
+			modify: func(seg *Segment) {
+				seg.Annotations["map"] = map[string]interface{}{}
+			},
+			want: []string{"annotations.map"},
+		},
+		{
+			name: "invalid precursor id",
+			modify: func(seg *Segment) {
+				seg.Subsegments[0].PrecursorIDs = []string{"foobar"}
+			},
+			want: []string{"subsegments[0].precursor_ids[0]"},
+		},
+		{
+			name: "subsegment without parent id",
+			modify: func(seg *Segment) {
+				seg.Type = "subsegment"
+			},
+			want: []string{"parent_id"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			seg := validSegment()
+			tt.modify(seg)
+			err := Validate(seg)
+			errs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("want ValidationErrors, got %#v", err)
+			}
+			var got []string
+			for _, err := range errs {
+				got = append(got, err.Path)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("paths mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,15 +2,16 @@ package xray
 
 import (
 	"context"
-	"crypto/rand"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/ctxmissing"
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
@@ -119,6 +120,12 @@ type Segment struct {
 	closedSegments  int
 	emittedSegments int
 
+	// estimated size of completed subsegments that are not emitted yet, used in the root.
+	pendingBytes int
+
+	// whether the age of the segment exceeded the limit of the streaming strategy, used in the root.
+	ageExceeded bool
+
 	// error information
 	error    bool
 	throttle bool
@@ -137,12 +144,7 @@ type Segment struct {
 
 // NewTraceID generates a string format of random trace ID.
 func NewTraceID() string {
-	var r [12]byte
-	_, err := rand.Read(r[:])
-	if err != nil {
-		panic(err)
-	}
-	return fmt.Sprintf("1-%08x-%x", nowFunc().Unix(), r)
+	return defaultIDGenerator.NewTraceID()
 }
 
 func withTraceID(ctx context.Context, traceID string) context.Context {
@@ -160,12 +162,7 @@ func ContextTraceID(ctx context.Context) string {
 
 // NewSegmentID generates a string format of segment ID.
 func NewSegmentID() string {
-	var r [8]byte
-	_, err := rand.Read(r[:])
-	if err != nil {
-		panic(err)
-	}
-	return fmt.Sprintf("%x", r)
+	return defaultIDGenerator.NewSegmentID()
 }
 
 // ContextSegment return the segment of current context.
@@ -191,7 +188,7 @@ func BeginDummySegment(ctx context.Context) (context.Context, *Segment) {
 //
 // Caller should close the segment when the work is done.
 func BeginSegment(ctx context.Context, name string) (context.Context, *Segment) {
-	return beginSegment(ctx, name, TraceHeader{}, nil)
+	return beginSegment(ctx, name, TraceHeader{}, nil, "")
 }
 
 // BeginSegmentWithRequest creates a new Segment for a given name and context.
@@ -199,7 +196,16 @@ func BeginSegment(ctx context.Context, name string) (context.Context, *Segment)
 //
 // Caller should close the segment when the work is done.
 func BeginSegmentWithRequest(ctx context.Context, name string, r *http.Request) (context.Context, *Segment) {
-	return beginSegment(ctx, name, TraceHeader{}, r)
+	return beginSegment(ctx, name, TraceHeader{}, r, "")
+}
+
+// BeginSegmentWithRoute is same as BeginSegmentWithRequest,
+// but the route is used as the URL for sampling instead of the path of the request, e.g. "/users/{id}".
+// If route is empty, the path of the request is used.
+//
+// Caller should close the segment when the work is done.
+func BeginSegmentWithRoute(ctx context.Context, name, route string, r *http.Request) (context.Context, *Segment) {
+	return beginSegment(ctx, name, TraceHeader{}, r, route)
 }
 
 // BeginSegmentWithHeader creates a new Segment for a given name, context, and trace header.
@@ -208,29 +214,29 @@ func BeginSegmentWithRequest(ctx context.Context, name string, r *http.Request)
 //
 // Caller should close the segment when the work is done.
 func BeginSegmentWithHeader(ctx context.Context, name, header string) (context.Context, *Segment) {
-	return beginSegment(ctx, name, ParseTraceHeader(header), nil)
+	return beginSegment(ctx, name, ParseTraceHeader(header), nil, "")
 }
 
-func beginSegment(ctx context.Context, name string, h TraceHeader, r *http.Request) (context.Context, *Segment) {
+func beginSegment(ctx context.Context, name string, h TraceHeader, r *http.Request, route string) (context.Context, *Segment) {
 	// inject trace id into the context
+	client := ContextClient(ctx)
 	if r != nil {
 		h = ParseTraceHeader(r.Header.Get(TraceIDHeaderKey))
 	}
 	if h.TraceID == "" {
-		h.TraceID = NewTraceID()
+		h.TraceID = client.idGenerator.NewTraceID()
 	}
 	ctx = withTraceID(ctx, h.TraceID)
 
 	// return dummy segment if X-Ray SDK is disabled.
-	client := ContextClient(ctx)
-	if client != nil && client.disabled {
+	if client.disabled {
 		return BeginDummySegment(ctx)
 	}
 
 	seg := &Segment{
 		ctx:           ctx,
 		name:          sanitizeSegmentName(name),
-		id:            NewSegmentID(),
+		id:            client.idGenerator.NewSegmentID(),
 		startTime:     nowFunc(),
 		totalSegments: 1,
 		origin:        origin(),
@@ -246,9 +252,13 @@ func beginSegment(ctx context.Context, name string, h TraceHeader, r *http.Reque
 		case SamplingDecisionNotSampled:
 			xraylog.Debug(ctx, "Incoming header decided: Sampled=false")
 		default:
+			url := r.URL.Path
+			if route != "" {
+				url = route
+			}
 			sd := client.samplingStrategy.ShouldTrace(&sampling.Request{
 				Host:        r.Host,
-				URL:         r.URL.Path,
+				URL:         url,
 				Method:      r.Method,
 				ServiceName: seg.name,
 				ServiceType: seg.origin,
@@ -273,11 +283,19 @@ func beginSegment(ctx context.Context, name string, h TraceHeader, r *http.Reque
 	if seg.sampled {
 		h.SamplingDecision = SamplingDecisionSampled
 	} else {
-		return BeginDummySegment(ctx)
+		atomic.AddUint64(&client.stats.segmentsDroppedBySampling, 1)
+		if !client.recordUnsampled {
+			return BeginDummySegment(ctx)
+		}
+		// the segment is never sent to the daemon, but it is recorded by the metrics recorder.
+		h.SamplingDecision = SamplingDecisionNotSampled
 	}
 
 	seg.traceID = h.TraceID
 	seg.traceHeader = h
+	if seg.sampled {
+		client.addSegment(seg)
+	}
 
 	return WithSegment(ctx, seg), seg
 }
@@ -286,20 +304,36 @@ func beginSegment(ctx context.Context, name string, h TraceHeader, r *http.Reque
 //
 // Caller should close the segment when the work is done.
 func BeginSubsegment(ctx context.Context, name string) (context.Context, *Segment) {
-	now := nowFunc()
+	return beginSubsegment(ctx, name, nowFunc())
+}
 
+// BeginSubsegmentAt creates a new Segment for a given name and context, that started at the start time.
+// It is used for recording the work whose timing is learned after the fact.
+// If start is zero, the current time is used.
+//
+// Caller should close the segment when the work is done.
+func BeginSubsegmentAt(ctx context.Context, name string, start time.Time) (context.Context, *Segment) {
+	if start.IsZero() {
+		start = nowFunc()
+	}
+	return beginSubsegment(ctx, name, start)
+}
+
+func beginSubsegment(ctx context.Context, name string, now time.Time) (context.Context, *Segment) {
 	value := ctx.Value(segmentContextKey)
 	if value == nil {
 		if header := ctx.Value(lambdaContextKey); header != nil {
 			// trace header comes from the AWS Lambda context.
-			return beginSubsegmentForLambda(ctx, header.(string), name)
+			return beginSubsegmentForLambda(ctx, header.(string), name, now)
 		}
 
-		client := defaultClient
-		if c := ctx.Value(clientContextKey); c != nil {
-			client = c.(*Client)
+		client := ContextClient(ctx)
+		atomic.AddUint64(&client.stats.contextMissing, 1)
+		missingCtx := ctxmissing.WithSegmentName(ctx, name)
+		if id := ContextTraceID(ctx); id != "" {
+			missingCtx = ctxmissing.WithTraceID(missingCtx, id)
 		}
-		client.contextMissingStrategy.ContextMissing(ctx, "context missing for "+name)
+		client.contextMissingStrategy.ContextMissing(missingCtx, "failed to begin subsegment")
 		return ctx, nil
 	}
 	parent := value.(*Segment)
@@ -311,7 +345,7 @@ func BeginSubsegment(ctx context.Context, name string) (context.Context, *Segmen
 	seg := &Segment{
 		ctx:       ctx,
 		name:      sanitizeSegmentName(name),
-		id:        NewSegmentID(),
+		id:        ContextClient(ctx).idGenerator.NewSegmentID(),
 		parent:    parent,
 		root:      root,
 		traceID:   parent.traceID,
@@ -321,6 +355,11 @@ func BeginSubsegment(ctx context.Context, name string) (context.Context, *Segmen
 
 	root.mu.Lock()
 	defer root.mu.Unlock()
+	if !root.sampled {
+		// the segment is never sent, and the metrics recorder uses only the root segment.
+		// so the subsegment is not added into the tree.
+		return ctx, seg
+	}
 	if parent != root {
 		parent.mu.Lock()
 		defer parent.mu.Unlock()
@@ -331,6 +370,22 @@ func BeginSubsegment(ctx context.Context, name string) (context.Context, *Segmen
 	return ctx, seg
 }
 
+// ID returns the ID of the segment.
+func (seg *Segment) ID() string {
+	if seg == nil {
+		return ""
+	}
+	return seg.id
+}
+
+// TraceID returns the trace ID of the segment.
+func (seg *Segment) TraceID() string {
+	if seg == nil {
+		return ""
+	}
+	return seg.traceID
+}
+
 // Sampled returns whether the current segment is sampled.
 func (seg *Segment) Sampled() bool {
 	if seg == nil {
@@ -355,26 +410,76 @@ func (seg *Segment) Close() {
 	if seg == nil {
 		return
 	}
-	if !seg.close() {
+	if !seg.close(nowFunc()) {
+		// seg is already closed
+		return
+	}
+	seg.afterClose(recover())
+}
+
+// CloseAt closes the segment at the end time.
+// It is used for recording the work whose timing is learned after the fact.
+// If end is zero, the current time is used.
+// If end is before the start time, the start time is used instead.
+func (seg *Segment) CloseAt(end time.Time) {
+	if seg == nil {
+		return
+	}
+	if end.IsZero() {
+		end = nowFunc()
+	}
+	if end.Before(seg.startTime) {
+		xraylog.WarnAttrs(
+			seg.ctx, "the end time is before the start time, the start time is used instead",
+			xraylog.String("segment_name", seg.name), xraylog.String("trace_id", seg.traceID),
+		)
+		end = seg.startTime
+	}
+	if !seg.close(end) {
 		// seg is already closed
 		return
 	}
+	seg.afterClose(recover())
+}
+
+// afterClose emits the closed segment. If err is not nil, it panics again after emitting.
+func (seg *Segment) afterClose(err interface{}) {
 	if seg.parent != nil {
 		xraylog.Debugf(seg.ctx, "Closing subsegment named %s", seg.name)
 	} else {
 		xraylog.Debugf(seg.ctx, "Closing segment named %s", seg.name)
 	}
-	err := recover()
 	seg.AddPanic(err)
 	if seg.Sampled() {
 		seg.emit()
 	}
+	if seg.isRoot() {
+		seg.client().recordMetrics(seg)
+		seg.client().removeSegment(seg)
+	}
 	if err != nil {
 		panic(err)
 	}
 }
 
-func (seg *Segment) close() bool {
+// AddCompletedSubsegment adds the subsegment that is already completed.
+// It is used for recording the work whose timing is learned after the fact,
+// e.g. queue wait time from the SentTimestamp attribute of Amazon SQS.
+func (seg *Segment) AddCompletedSubsegment(name string, start, end time.Time) {
+	if seg == nil {
+		return
+	}
+	_, sub := BeginSubsegmentAt(WithSegment(seg.ctx, seg), name, start)
+	sub.CloseAt(end)
+}
+
+// AddCompletedSubsegment adds the subsegment that is already completed to the segment of the current context.
+func AddCompletedSubsegment(ctx context.Context, name string, start, end time.Time) {
+	_, sub := BeginSubsegmentAt(ctx, name, start)
+	sub.CloseAt(end)
+}
+
+func (seg *Segment) close(end time.Time) bool {
 	root := seg.root
 	root.mu.Lock()
 	defer root.mu.Unlock()
@@ -386,7 +491,7 @@ func (seg *Segment) close() bool {
 		return false
 	}
 	root.closedSegments++
-	seg.endTime = nowFunc()
+	seg.endTime = end
 	return true
 }
 
@@ -411,15 +516,6 @@ func (seg *Segment) client() *Client {
 	return ContextClient(seg.ctx)
 }
 
-func newExceptionID() string {
-	var r [8]byte
-	_, err := rand.Read(r[:])
-	if err != nil {
-		panic(err)
-	}
-	return fmt.Sprintf("%x", r)
-}
-
 // AddError sets error.
 func (seg *Segment) AddError(err error) bool {
 	if seg == nil {
@@ -428,6 +524,7 @@ func (seg *Segment) AddError(err error) bool {
 	if err == nil {
 		return false
 	}
+	id := seg.client().idGenerator.NewSegmentID()
 	seg.mu.Lock()
 	defer seg.mu.Unlock()
 
@@ -437,7 +534,7 @@ func (seg *Segment) AddError(err error) bool {
 	}
 	seg.cause.WorkingDirectory, _ = os.Getwd()
 	seg.cause.Exceptions = append(seg.cause.Exceptions, schema.Exception{
-		ID:      newExceptionID(),
+		ID:      id,
 		Type:    fmt.Sprintf("%T", err),
 		Message: err.Error(),
 	})
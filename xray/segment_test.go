@@ -237,6 +237,88 @@ func TestBeginSubsegment_contextMissing(t *testing.T) {
 	}
 }
 
+func TestBeginSubsegmentAt(t *testing.T) {
+	nowFunc = fixedTime
+	defer func() { nowFunc = time.Now }()
+
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+
+	ctx, root := BeginSegment(ctx, "root")
+	_, seg := BeginSubsegmentAt(ctx, "subsegment", fixedTime().Add(-2*time.Second))
+	seg.CloseAt(fixedTime().Add(-time.Second))
+	root.AddCompletedSubsegment("completed", fixedTime().Add(time.Second), fixedTime().Add(3*time.Second))
+	root.Close()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Error(err)
+	}
+	want := &schema.Segment{
+		Name:      "root",
+		ID:        root.id,
+		TraceID:   root.traceID,
+		StartTime: 1000000000,
+		EndTime:   1000000000,
+		Subsegments: []*schema.Segment{
+			{
+				Name:      "subsegment",
+				ID:        seg.id,
+				StartTime: 999999998,
+				EndTime:   999999999,
+			},
+			{
+				Name:      "completed",
+				ID:        got.Subsegments[1].ID,
+				StartTime: 1000000001,
+				EndTime:   1000000003,
+			},
+		},
+		Service: ServiceData,
+		AWS:     xrayData,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloseAt_BeforeStart(t *testing.T) {
+	nowFunc = fixedTime
+	defer func() { nowFunc = time.Now }()
+
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+
+	ctx, root := BeginSegment(ctx, "root")
+	root.AddCompletedSubsegment("negative", fixedTime().Add(time.Second), fixedTime().Add(-time.Second))
+	root.Close()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &schema.Segment{
+		Name:      "root",
+		ID:        root.id,
+		TraceID:   root.traceID,
+		StartTime: 1000000000,
+		EndTime:   1000000000,
+		Subsegments: []*schema.Segment{
+			{
+				Name:      "negative",
+				ID:        got.Subsegments[0].ID,
+				StartTime: 1000000001,
+				EndTime:   1000000001,
+			},
+		},
+		Service: ServiceData,
+		AWS:     xrayData,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestSegmentPanic(t *testing.T) {
 	nowFunc = fixedTime
 	defer func() { nowFunc = time.Now }()
@@ -0,0 +1,94 @@
+package xray
+
+import (
+	"expvar"
+	"sync/atomic"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+// Stats is a snapshot of the internal metrics of the client.
+type Stats struct {
+	// the number of segment documents sent to the daemon.
+	SegmentsEmitted uint64 `json:"segments_emitted"`
+
+	// the number of bytes sent to the daemon.
+	BytesSent uint64 `json:"bytes_sent"`
+
+	// the number of segment documents that failed to encode.
+	EncodeErrors uint64 `json:"encode_errors"`
+
+	// the number of failures of dialing to the daemon.
+	DialErrors uint64 `json:"dial_errors"`
+
+	// the number of failures of writing to the daemon.
+	WriteErrors uint64 `json:"write_errors"`
+
+	// the number of segments that are not sampled.
+	SegmentsDroppedBySampling uint64 `json:"segments_dropped_by_sampling"`
+
+	// the number of context missing events.
+	ContextMissing uint64 `json:"context_missing"`
+
+	// the number of failures of refreshing the sampling rules and quotas.
+	// it is reported only if the sampling strategy implements sampling.RefreshFailureCounter.
+	SamplingRefreshFailures uint64 `json:"sampling_refresh_failures"`
+
+	// the number of items dropped by the emitter, e.g. when its queue is full.
+	// it is reported only if the emitter implements DropCounter.
+	EmitterDropped uint64 `json:"emitter_dropped"`
+}
+
+// Each calls f for each metric in the stats.
+// The names are same as the keys of JSON representation.
+func (s Stats) Each(f func(name string, value uint64)) {
+	f("segments_emitted", s.SegmentsEmitted)
+	f("bytes_sent", s.BytesSent)
+	f("encode_errors", s.EncodeErrors)
+	f("dial_errors", s.DialErrors)
+	f("write_errors", s.WriteErrors)
+	f("segments_dropped_by_sampling", s.SegmentsDroppedBySampling)
+	f("context_missing", s.ContextMissing)
+	f("sampling_refresh_failures", s.SamplingRefreshFailures)
+	f("emitter_dropped", s.EmitterDropped)
+}
+
+// clientStats is the internal counters of the client.
+// it is allocated separately from Client, to make sure 64-bit alignment for atomic operations.
+type clientStats struct {
+	segmentsEmitted           uint64
+	bytesSent                 uint64
+	encodeErrors              uint64
+	dialErrors                uint64
+	writeErrors               uint64
+	segmentsDroppedBySampling uint64
+	contextMissing            uint64
+}
+
+// Stats returns the snapshot of the internal metrics of the client.
+func (c *Client) Stats() Stats {
+	s := Stats{
+		SegmentsEmitted:           atomic.LoadUint64(&c.stats.segmentsEmitted),
+		BytesSent:                 atomic.LoadUint64(&c.stats.bytesSent),
+		EncodeErrors:              atomic.LoadUint64(&c.stats.encodeErrors),
+		DialErrors:                atomic.LoadUint64(&c.stats.dialErrors),
+		WriteErrors:               atomic.LoadUint64(&c.stats.writeErrors),
+		SegmentsDroppedBySampling: atomic.LoadUint64(&c.stats.segmentsDroppedBySampling),
+		ContextMissing:            atomic.LoadUint64(&c.stats.contextMissing),
+	}
+	if counter, ok := c.samplingStrategy.(sampling.RefreshFailureCounter); ok {
+		s.SamplingRefreshFailures = counter.RefreshFailures()
+	}
+	if counter, ok := c.emitter.(DropCounter); ok {
+		s.EmitterDropped = counter.Dropped()
+	}
+	return s
+}
+
+// PublishExpvar publishes the internal metrics of the client as an expvar variable named name.
+// It panics if the name is already registered, same as expvar.Publish.
+func (c *Client) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return c.Stats()
+	}))
+}
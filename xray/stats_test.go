@@ -0,0 +1,112 @@
+package xray
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+type neverSampleStrategy struct{}
+
+func (neverSampleStrategy) ShouldTrace(req *sampling.Request) *sampling.Decision {
+	return &sampling.Decision{Sample: false}
+}
+
+func TestClient_Stats(t *testing.T) {
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+	client := ContextClient(ctx)
+
+	_, seg := BeginSegment(ctx, "foobar")
+	seg.Close()
+	if _, err := td.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// context missing
+	_, sub := BeginSubsegment(context.Background(), "context-missing")
+	sub.Close()
+	_, sub = BeginSubsegment(WithClient(context.Background(), client), "context-missing")
+	sub.Close()
+
+	// dropped by sampling
+	client.samplingStrategy = neverSampleStrategy{}
+	_, seg = BeginSegment(ctx, "not-sampled")
+	seg.Close()
+
+	got := client.Stats()
+	if got.BytesSent == 0 {
+		t.Error("want BytesSent is not zero, got zero")
+	}
+	got.BytesSent = 0
+	want := Stats{
+		SegmentsEmitted:           1,
+		SegmentsDroppedBySampling: 1,
+		ContextMissing:            1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStats_Each(t *testing.T) {
+	stats := Stats{
+		SegmentsEmitted:           1,
+		BytesSent:                 2,
+		EncodeErrors:              3,
+		DialErrors:                4,
+		WriteErrors:               5,
+		SegmentsDroppedBySampling: 6,
+		ContextMissing:            7,
+		SamplingRefreshFailures:   8,
+		EmitterDropped:            9,
+	}
+
+	got := map[string]uint64{}
+	stats.Each(func(name string, value uint64) {
+		got[name] = value
+	})
+
+	// the names are same as the keys of JSON representation.
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint64{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// the number of the runs of TestClient_PublishExpvar.
+// expvar panics if the name is reused, so each run publishes a different name, e.g. for go test -count=2.
+var publishExpvarRuns int
+
+func TestClient_PublishExpvar(t *testing.T) {
+	ctx, td := NewTestDaemon(nil)
+	defer td.Close()
+	client := ContextClient(ctx)
+	publishExpvarRuns++
+	name := "xray_test_publish_expvar_" + strconv.Itoa(publishExpvarRuns)
+	client.PublishExpvar(name)
+
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatal("expvar is not published")
+	}
+	var got Stats
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Stats{}, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
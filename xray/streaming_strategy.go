@@ -1,6 +1,9 @@
 package xray
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
 )
 
@@ -72,7 +75,9 @@ func serialize(seg *Segment) *schema.Segment {
 
 	for _, sub := range seg.subsegments {
 		sub.mu.Lock()
-		ret.Subsegments = append(ret.Subsegments, serialize(sub))
+		if sub.status != segmentStatusEmitted {
+			ret.Subsegments = append(ret.Subsegments, serialize(sub))
+		}
 		sub.mu.Unlock()
 	}
 
@@ -85,6 +90,32 @@ func serialize(seg *Segment) *schema.Segment {
 	return ret
 }
 
+// serializeRemaining serializes the segments that are not emitted yet as independent documents.
+// It is used for flushing the segments that are not closed at shutdown.
+func serializeRemaining(root *Segment) []*schema.Segment {
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	var result []*schema.Segment
+	var walk func(seg *Segment)
+	walk = func(seg *Segment) {
+		if seg.status != segmentStatusEmitted {
+			result = append(result, serializeIndependentSubsegment(seg))
+			if !seg.inProgress() {
+				seg.status = segmentStatusEmitted
+				seg.root.emittedSegments++
+			}
+		}
+		for _, sub := range seg.subsegments {
+			sub.mu.Lock()
+			walk(sub)
+			sub.mu.Unlock()
+		}
+	}
+	walk(root)
+	return result
+}
+
 type streamingStrategyLimitSubsegment struct {
 	limit int
 }
@@ -186,3 +217,133 @@ func (ctx *streamingStrategyLimitSubsegmentContext) serialize(seg *Segment) {
 		sub.mu.Unlock()
 	}
 }
+
+type streamingStrategyLimitAgeSize struct {
+	maxAge   time.Duration
+	maxBytes int
+}
+
+// NewStreamingStrategyLimitAgeSize returns a streaming strategy.
+// It sends completed subsegments independently once the root segment has been open for longer than maxAge,
+// or once the estimated size of completed subsegments that are not sent yet exceeds maxBytes.
+// A subsegment whose descendants are all completed is sent as one document together with its descendants.
+// Zero maxAge or zero maxBytes disables the limit.
+func NewStreamingStrategyLimitAgeSize(maxAge time.Duration, maxBytes int) StreamingStrategy {
+	if maxAge < 0 {
+		panic("xray: maxAge should not be negative")
+	}
+	if maxBytes < 0 {
+		panic("xray: maxBytes should not be negative")
+	}
+	return &streamingStrategyLimitAgeSize{
+		maxAge:   maxAge,
+		maxBytes: maxBytes,
+	}
+}
+
+func (s *streamingStrategyLimitAgeSize) StreamSegment(seg *Segment) []*schema.Segment {
+	root := seg.root
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	if seg.isRoot() {
+		// fast pass for batching all subsegments.
+		if root.emittedSegments == 0 {
+			return []*schema.Segment{serialize(seg)}
+		}
+		return s.flush(root)
+	}
+
+	// the size is estimated only when the limit is enabled, because it needs encoding the segment.
+	var size int
+	if s.maxBytes > 0 {
+		seg.mu.Lock()
+		size = estimateSize(seg)
+		seg.mu.Unlock()
+		root.pendingBytes += size
+		if root.pendingBytes > s.maxBytes {
+			return s.flush(root)
+		}
+	}
+
+	if s.maxAge == 0 || nowFunc().Sub(root.startTime) <= s.maxAge {
+		return nil
+	}
+	if !root.ageExceeded {
+		root.ageExceeded = true
+		return s.flush(root)
+	}
+
+	// the completed subsegments are already sent when the age exceeded the limit,
+	// so search only the subtree of seg instead of the whole tree.
+	seg.mu.Lock()
+	defer seg.mu.Unlock()
+	ctx := &streamingStrategyLimitAgeSizeContext{}
+	ctx.serialize(seg)
+	if len(ctx.result) > 0 {
+		root.pendingBytes -= size
+	}
+	return ctx.result
+}
+
+// flush sends all completed subsegments that are not sent yet. root.mu should be locked.
+func (s *streamingStrategyLimitAgeSize) flush(root *Segment) []*schema.Segment {
+	ctx := &streamingStrategyLimitAgeSizeContext{}
+	ctx.serialize(root)
+	root.pendingBytes = 0
+	return ctx.result
+}
+
+// estimateSize returns the size of the segment document without its subsegments.
+func estimateSize(seg *Segment) int {
+	data, err := json.Marshal(serializeIndependentSubsegment(seg))
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+type streamingStrategyLimitAgeSizeContext struct {
+	result []*schema.Segment
+}
+
+// search completed subtrees and emit them as a unit.
+func (ctx *streamingStrategyLimitAgeSizeContext) serialize(seg *Segment) {
+	if !seg.inProgress() && seg.status != segmentStatusEmitted {
+		if !seg.isRoot() && completed(seg) {
+			// all descendants are completed, emit them together.
+			ret := serialize(seg)
+			ret.TraceID = seg.traceID
+			ret.ParentID = seg.parent.id
+			ret.Type = "subsegment"
+			ctx.result = append(ctx.result, ret)
+			return
+		}
+		ctx.result = append(ctx.result, serializeIndependentSubsegment(seg))
+		seg.status = segmentStatusEmitted
+		seg.root.emittedSegments++
+	}
+
+	for _, sub := range seg.subsegments {
+		sub.mu.Lock()
+		ctx.serialize(sub)
+		sub.mu.Unlock()
+	}
+}
+
+// completed returns whether seg and all of its descendants are completed and not emitted yet.
+// seg.mu should be locked.
+func completed(seg *Segment) bool {
+	if seg.inProgress() || seg.status == segmentStatusEmitted {
+		return false
+	}
+	for _, sub := range seg.subsegments {
+		sub.mu.Lock()
+		ok := completed(sub)
+		sub.mu.Unlock()
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -207,3 +207,160 @@ func TestStreamingStrategyLimitSubsegment(t *testing.T) {
 		}
 	})
 }
+
+func TestStreamingStrategyLimitAgeSize(t *testing.T) {
+	now := time.Date(2001, time.September, 9, 1, 46, 40, 0, time.UTC)
+
+	// create the segment that have a completed subtree.
+	newSegment := func() *Segment {
+		seg := &Segment{
+			name:           "root segment",
+			id:             "03babb4ba280be51",
+			traceID:        "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+			startTime:      now,
+			totalSegments:  3,
+			closedSegments: 2,
+		}
+		seg.root = seg
+		child := &Segment{
+			parent:    seg,
+			root:      seg,
+			name:      "child",
+			id:        "acc82ea453399569",
+			traceID:   seg.traceID,
+			startTime: now,
+			endTime:   now.Add(time.Second),
+		}
+		grandchild := &Segment{
+			parent:    child,
+			root:      seg,
+			name:      "grandchild",
+			id:        "bebb747c66f386a5",
+			traceID:   seg.traceID,
+			startTime: now,
+			endTime:   now.Add(time.Second),
+		}
+		seg.subsegments = append(seg.subsegments, child)
+		child.subsegments = append(child.subsegments, grandchild)
+		return seg
+	}
+	subtree := []*schema.Segment{
+		{
+			Name:      "child",
+			ID:        "acc82ea453399569",
+			ParentID:  "03babb4ba280be51",
+			Type:      "subsegment",
+			TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+			StartTime: 1000000000,
+			EndTime:   1000000001,
+			Subsegments: []*schema.Segment{
+				{
+					Name:      "grandchild",
+					ID:        "bebb747c66f386a5",
+					StartTime: 1000000000,
+					EndTime:   1000000001,
+				},
+			},
+		},
+	}
+
+	t.Run("within limits", func(t *testing.T) {
+		nowFunc = func() time.Time { return now.Add(time.Second) }
+		defer func() { nowFunc = time.Now }()
+
+		seg := newSegment()
+		strategy := NewStreamingStrategyLimitAgeSize(time.Minute, 1024*1024)
+		if got := strategy.StreamSegment(seg.subsegments[0]); got != nil {
+			t.Errorf("want nil, got %v", got)
+		}
+		if seg.emittedSegments != 0 {
+			t.Errorf("want %d, got %d", 0, seg.emittedSegments)
+		}
+	})
+
+	t.Run("exceed the size", func(t *testing.T) {
+		nowFunc = func() time.Time { return now.Add(time.Second) }
+		defer func() { nowFunc = time.Now }()
+
+		seg := newSegment()
+		strategy := NewStreamingStrategyLimitAgeSize(0, 1)
+		got := strategy.StreamSegment(seg.subsegments[0])
+		if diff := cmp.Diff(subtree, got); diff != "" {
+			t.Errorf("StreamSegment(seg) mismatch (-want +got):\n%s", diff)
+		}
+		if seg.emittedSegments != 2 {
+			t.Errorf("want %d, got %d", 2, seg.emittedSegments)
+		}
+		if seg.pendingBytes != 0 {
+			t.Errorf("want %d, got %d", 0, seg.pendingBytes)
+		}
+
+		// the root segment doesn't contain the subsegments that are already emitted.
+		seg.endTime = now.Add(2 * time.Second)
+		got = strategy.StreamSegment(seg)
+		want := []*schema.Segment{
+			{
+				Name:      "root segment",
+				ID:        "03babb4ba280be51",
+				TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+				StartTime: 1000000000,
+				EndTime:   1000000002,
+				Service:   ServiceData,
+				AWS: schema.AWS{
+					"xray": &schema.XRay{
+						SDKVersion: Version,
+						SDK:        Name,
+					},
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("StreamSegment(seg) mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("exceed the age", func(t *testing.T) {
+		nowFunc = func() time.Time { return now.Add(time.Hour) }
+		defer func() { nowFunc = time.Now }()
+
+		seg := newSegment()
+		strategy := NewStreamingStrategyLimitAgeSize(time.Minute, 0)
+		got := strategy.StreamSegment(seg.subsegments[0])
+		if diff := cmp.Diff(subtree, got); diff != "" {
+			t.Errorf("StreamSegment(seg) mismatch (-want +got):\n%s", diff)
+		}
+		if seg.pendingBytes != 0 {
+			t.Errorf("want %d, got %d", 0, seg.pendingBytes)
+		}
+
+		// the subsegments completed after that are sent without searching the whole tree.
+		other := &Segment{
+			parent:    seg,
+			root:      seg,
+			name:      "other",
+			id:        "f3e3bb3a6b9bfcd4",
+			traceID:   seg.traceID,
+			startTime: now,
+			endTime:   now.Add(time.Second),
+		}
+		seg.subsegments = append(seg.subsegments, other)
+		got = strategy.StreamSegment(other)
+		want := []*schema.Segment{
+			{
+				Name:      "other",
+				ID:        "f3e3bb3a6b9bfcd4",
+				ParentID:  "03babb4ba280be51",
+				Type:      "subsegment",
+				TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+				StartTime: 1000000000,
+				EndTime:   1000000001,
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("StreamSegment(seg) mismatch (-want +got):\n%s", diff)
+		}
+		if seg.emittedSegments != 3 {
+			t.Errorf("want %d, got %d", 3, seg.emittedSegments)
+		}
+	})
+}
@@ -28,6 +28,7 @@ type TestDaemon struct {
 	ctx       context.Context
 	cancel    context.CancelFunc
 	ts        *httptest.Server
+	client    *Client
 	closeOnce sync.Once
 }
 
@@ -57,11 +58,12 @@ func NewTestDaemon(handler http.Handler) (context.Context, *TestDaemon) {
 		address += " tcp:" + u.Host
 	}
 
-	ctx = context.WithValue(ctx, clientContextKey, New(&Config{
+	d.client = New(&Config{
 		DaemonAddress:          address,
 		SamplingStrategy:       sampling.NewAllStrategy(),
 		ContextMissingStrategy: &testDaemonContextMissing{td: d},
-	}))
+	})
+	ctx = context.WithValue(ctx, clientContextKey, d.client)
 
 	go d.run(c)
 	return ctx, d
@@ -85,6 +87,7 @@ type result struct {
 // Close shutdowns the daemon.
 func (td *TestDaemon) Close() {
 	td.closeOnce.Do(func() {
+		td.client.Close()
 		td.cancel()
 		td.conn.Close()
 		if td.ts != nil {
@@ -145,6 +148,7 @@ type NullDaemon struct {
 	conn      net.PacketConn
 	ctx       context.Context
 	cancel    context.CancelFunc
+	client    *Client
 	closeOnce sync.Once
 }
 
@@ -164,11 +168,12 @@ func NewNullDaemon() (context.Context, *NullDaemon) {
 	}
 	address := "udp:" + conn.LocalAddr().String()
 
-	ctx = context.WithValue(ctx, clientContextKey, New(&Config{
+	d.client = New(&Config{
 		DaemonAddress:          address,
 		SamplingStrategy:       sampling.NewAllStrategy(),
 		ContextMissingStrategy: &ctxmissing.LogErrorStrategy{},
-	}))
+	})
+	ctx = context.WithValue(ctx, clientContextKey, d.client)
 
 	go d.run()
 	return ctx, d
@@ -188,6 +193,7 @@ func (td *NullDaemon) run() {
 // Close shutdowns the daemon.
 func (td *NullDaemon) Close() {
 	td.closeOnce.Do(func() {
+		td.client.Close()
 		td.cancel()
 		td.conn.Close()
 	})
@@ -0,0 +1,106 @@
+package xraylog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Attr is a key-value pair of structured logs.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns an Attr for a string value.
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Int64 returns an Attr for an int64 value.
+func Int64(key string, value int64) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Float64 returns an Attr for a float64 value.
+func Float64(key string, value float64) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Bool returns an Attr for a bool value.
+func Bool(key string, value bool) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Any returns an Attr for any value.
+func Any(key string, value interface{}) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Err returns an Attr for an error, and its key is "error".
+func Err(err error) Attr {
+	var value interface{}
+	if err != nil {
+		value = err.Error()
+	}
+	return Attr{Key: "error", Value: value}
+}
+
+// StructuredLogger is the logging interface that accepts structured attributes.
+// If the logger implements StructuredLogger, the SDK passes the attributes to LogAttrs.
+// Otherwise, the attributes are formatted into the message, and passed to Log.
+type StructuredLogger interface {
+	Logger
+
+	// LogAttrs outputs the msg and the attributes into the log.
+	// It may be called concurrently from multiple goroutines.
+	LogAttrs(ctx context.Context, level LogLevel, msg string, attrs ...Attr)
+}
+
+// LogAttrs outputs the log message with the attributes.
+func LogAttrs(ctx context.Context, level LogLevel, msg string, attrs ...Attr) {
+	logger := ContextLogger(ctx)
+	if l, ok := logger.(StructuredLogger); ok {
+		l.LogAttrs(ctx, level, msg, attrs...)
+		return
+	}
+	logger.Log(ctx, level, attrsArgs{msg: msg, attrs: attrs})
+}
+
+// DebugAttrs outputs debug level log message with the attributes.
+func DebugAttrs(ctx context.Context, msg string, attrs ...Attr) {
+	LogAttrs(ctx, LogLevelDebug, msg, attrs...)
+}
+
+// InfoAttrs outputs info level log message with the attributes.
+func InfoAttrs(ctx context.Context, msg string, attrs ...Attr) {
+	LogAttrs(ctx, LogLevelInfo, msg, attrs...)
+}
+
+// WarnAttrs outputs warn level log message with the attributes.
+func WarnAttrs(ctx context.Context, msg string, attrs ...Attr) {
+	LogAttrs(ctx, LogLevelWarn, msg, attrs...)
+}
+
+// ErrorAttrs outputs error level log message with the attributes.
+func ErrorAttrs(ctx context.Context, msg string, attrs ...Attr) {
+	LogAttrs(ctx, LogLevelError, msg, attrs...)
+}
+
+// attrsArgs formats the attributes into the "msg key1=value1 key2=value2" format.
+type attrsArgs struct {
+	msg   string
+	attrs []Attr
+}
+
+func (args attrsArgs) String() string {
+	var builder strings.Builder
+	builder.WriteString(args.msg)
+	for _, attr := range args.attrs {
+		builder.WriteByte(' ')
+		builder.WriteString(attr.Key)
+		builder.WriteByte('=')
+		fmt.Fprint(&builder, attr.Value)
+	}
+	return builder.String()
+}
@@ -0,0 +1,27 @@
+package xraylog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLogAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDefaultLogger(&buf, LogLevelInfo)
+	ctx := WithLogger(context.Background(), logger)
+
+	DebugAttrs(ctx, "debug", String("foo", "bar"))
+	ErrorAttrs(ctx, "failed to write", Err(errors.New("some error")), String("trace_id", "1-5e645f3e-1dfad076a177c5ccc5de12f5"), Int64("count", 42))
+
+	lines := strings.Split(buf.String(), "\n")
+	want := " [ERROR] failed to write error=some error trace_id=1-5e645f3e-1dfad076a177c5ccc5de12f5 count=42"
+	if !strings.HasSuffix(lines[0], want) {
+		t.Errorf("want suffix %q, got %q", want, lines[0])
+	}
+	if len(lines) != 2 {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+}
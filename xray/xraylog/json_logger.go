@@ -0,0 +1,77 @@
+package xraylog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type jsonLogger struct {
+	mu       sync.Mutex
+	w        io.Writer
+	minLevel LogLevel
+	pool     sync.Pool
+}
+
+// NewJSONLogger returns new logger that outputs JSON lines into w.
+// Each line has "time", "level" and "msg" fields, and the fields from the attributes.
+func NewJSONLogger(w io.Writer, minLevel LogLevel) StructuredLogger {
+	return &jsonLogger{
+		w:        w,
+		minLevel: minLevel,
+		pool: sync.Pool{
+			New: func() interface{} {
+				return new(bytes.Buffer)
+			},
+		},
+	}
+}
+
+// Log implements Logger.
+func (l *jsonLogger) Log(ctx context.Context, level LogLevel, msg fmt.Stringer) {
+	if level < l.minLevel {
+		return
+	}
+	l.LogAttrs(ctx, level, msg.String())
+}
+
+// LogAttrs implements StructuredLogger.
+func (l *jsonLogger) LogAttrs(ctx context.Context, level LogLevel, msg string, attrs ...Attr) {
+	if level < l.minLevel {
+		return
+	}
+
+	buf := l.pool.Get().(*bytes.Buffer)
+	defer l.pool.Put(buf)
+	buf.Reset()
+	buf.WriteString(`{"time":`)
+	writeJSON(buf, time.Now().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSON(buf, msg)
+	for _, attr := range attrs {
+		buf.WriteByte(',')
+		writeJSON(buf, attr.Key)
+		buf.WriteByte(':')
+		writeJSON(buf, attr.Value)
+	}
+	buf.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(buf.Bytes())
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// fallback to string
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(data)
+}
@@ -0,0 +1,65 @@
+package xraylog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf, LogLevelWarn)
+	ctx := WithLogger(context.Background(), logger)
+
+	Info(ctx, "info")
+	Warnf(ctx, "warn %d", 42)
+	ErrorAttrs(ctx, "error", Err(errors.New("some error")), String("rule_name", "Default"), Int64("count", 42))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+
+	var got []map[string]interface{}
+	for _, line := range lines {
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := v["time"].(string); !ok {
+			t.Errorf("time is not found: %s", line)
+		}
+		delete(v, "time")
+		got = append(got, v)
+	}
+	want := []map[string]interface{}{
+		{
+			"level": "WARN",
+			"msg":   "warn 42",
+		},
+		{
+			"level":     "ERROR",
+			"msg":       "error",
+			"error":     "some error",
+			"rule_name": "Default",
+			"count":     float64(42),
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func BenchmarkJSONLogger(b *testing.B) {
+	logger := NewJSONLogger(ioutil.Discard, LogLevelWarn)
+	ctx := WithLogger(context.Background(), logger)
+	for i := 0; i < b.N; i++ {
+		ErrorAttrs(ctx, "something wrong", String("foo", "bar"))
+	}
+}
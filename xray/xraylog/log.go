@@ -5,6 +5,7 @@
 // If AWS_XRAY_DEBUG_MODE is set, the log level is set to the debug level.
 // AWS_XRAY_LOG_LEVEL may be set to debug, info, warn, error or silent.
 // This value is ignored if AWS_XRAY_DEBUG_MODE is set.
+// If AWS_XRAY_LOG_FORMAT is set to json, the built in logger outputs JSON lines.
 package xraylog
 
 import (
@@ -51,6 +52,10 @@ func init() {
 			return
 		}
 	}
+	if strings.EqualFold(os.Getenv("AWS_XRAY_LOG_FORMAT"), "json") {
+		globalLogger = NewJSONLogger(os.Stderr, level)
+		return
+	}
 	globalLogger = NewDefaultLogger(os.Stderr, level)
 }
 
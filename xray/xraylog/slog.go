@@ -0,0 +1,60 @@
+//go:build go1.21
+// +build go1.21
+
+package xraylog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type slogLogger struct {
+	h slog.Handler
+}
+
+// NewSlogLogger returns a new logger that outputs the logs into h.
+// LogLevelDebug, LogLevelInfo, LogLevelWarn and LogLevelError are mapped to
+// slog.LevelDebug, slog.LevelInfo, slog.LevelWarn and slog.LevelError.
+func NewSlogLogger(h slog.Handler) StructuredLogger {
+	if h == nil {
+		panic("xraylog: handler should not be nil")
+	}
+	return &slogLogger{h: h}
+}
+
+// Log implements Logger.
+func (l *slogLogger) Log(ctx context.Context, level LogLevel, msg fmt.Stringer) {
+	if !l.h.Enabled(ctx, slogLevel(level)) {
+		return
+	}
+	l.LogAttrs(ctx, level, msg.String())
+}
+
+// LogAttrs implements StructuredLogger.
+func (l *slogLogger) LogAttrs(ctx context.Context, level LogLevel, msg string, attrs ...Attr) {
+	lv := slogLevel(level)
+	if !l.h.Enabled(ctx, lv) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), lv, msg, 0)
+	for _, attr := range attrs {
+		r.AddAttrs(slog.Any(attr.Key, attr.Value))
+	}
+	l.h.Handle(ctx, r)
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelInfo:
+		return slog.LevelInfo
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	}
+	return slog.Level(level)
+}
@@ -0,0 +1,39 @@
+//go:build go1.21
+// +build go1.21
+
+package xraylog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})
+	ctx := WithLogger(context.Background(), NewSlogLogger(h))
+
+	Debug(ctx, "debug")
+	ErrorAttrs(ctx, "failed to write", Err(errors.New("some error")), String("trace_id", "1-5e645f3e-1dfad076a177c5ccc5de12f5"))
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["level"] != "ERROR" {
+		t.Errorf("want %q, got %q", "ERROR", got["level"])
+	}
+	if got["msg"] != "failed to write" {
+		t.Errorf("want %q, got %q", "failed to write", got["msg"])
+	}
+	if got["error"] != "some error" {
+		t.Errorf("want %q, got %q", "some error", got["error"])
+	}
+	if got["trace_id"] != "1-5e645f3e-1dfad076a177c5ccc5de12f5" {
+		t.Errorf("want %q, got %q", "1-5e645f3e-1dfad076a177c5ccc5de12f5", got["trace_id"])
+	}
+}
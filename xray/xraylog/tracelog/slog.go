@@ -0,0 +1,93 @@
+//go:build go1.21
+// +build go1.21
+
+package tracelog
+
+import (
+	"context"
+	"log/slog"
+)
+
+type handler struct {
+	// h is the handler without groups.
+	h slog.Handler
+
+	// the groups and the attributes added after the first group, from outermost to innermost.
+	// they are applied in Handle, so that the trace ID is recorded at the top level.
+	goas []groupOrAttrs
+}
+
+// groupOrAttrs is a group name or a list of attributes.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewHandler returns a new slog.Handler that adds the trace ID associated with the context into every record,
+// and passes them to h. The trace ID is recorded with TraceIDKey at the top level, even if the logger has groups.
+func NewHandler(h slog.Handler) slog.Handler {
+	if h == nil {
+		panic("tracelog: handler should not be nil")
+	}
+	return &handler{h: h}
+}
+
+// Enabled implements slog.Handler.
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	id := TraceID(ctx)
+	if id == "" && len(h.goas) == 0 {
+		return h.h.Handle(ctx, r)
+	}
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group != "" {
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		} else {
+			attrs = append(goa.attrs[:len(goa.attrs):len(goa.attrs)], attrs...)
+		}
+	}
+
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	if id != "" {
+		record.AddAttrs(slog.String(TraceIDKey, id))
+	}
+	record.AddAttrs(attrs...)
+	return h.h.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	if len(h.goas) == 0 {
+		return &handler{h: h.h.WithAttrs(attrs)}
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements slog.Handler.
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *handler) with(goa groupOrAttrs) *handler {
+	goas := make([]groupOrAttrs, 0, len(h.goas)+1)
+	goas = append(goas, h.goas...)
+	goas = append(goas, goa)
+	return &handler{h: h.h, goas: goas}
+}
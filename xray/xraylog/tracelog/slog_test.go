@@ -0,0 +1,72 @@
+//go:build go1.21
+// +build go1.21
+
+package tracelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestHandler(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil)))
+
+	ctx, seg := xray.BeginSegment(ctx, "foobar")
+	defer seg.Close()
+	logger.InfoContext(ctx, "hello", "foo", "bar")
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := seg.TraceID() + "@" + seg.ID()
+	if got[TraceIDKey] != want {
+		t.Errorf("want %q, got %q", want, got[TraceIDKey])
+	}
+	if got["foo"] != "bar" {
+		t.Errorf("want %q, got %q", "bar", got["foo"])
+	}
+}
+
+func TestHandler_WithGroup(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil)))
+	logger = logger.With("service", "api").WithGroup("request").With("method", "GET").WithGroup("user")
+
+	ctx, seg := xray.BeginSegment(ctx, "foobar")
+	defer seg.Close()
+	logger.InfoContext(ctx, "hello", "id", 42)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"msg":      "hello",
+		"level":    "INFO",
+		"service":  "api",
+		TraceIDKey: seg.TraceID() + "@" + seg.ID(),
+		"request": map[string]interface{}{
+			"method": "GET",
+			"user": map[string]interface{}{
+				"id": 42.0,
+			},
+		},
+	}
+	delete(got, "time")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,56 @@
+// Package tracelog adds the X-Ray trace ID into the application logs.
+// CloudWatch ServiceLens correlates the logs that contain the trace ID with the traces.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/ServiceLens.html
+//
+// The following is an example for the log package.
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//	  logger := tracelog.Logger(r.Context(), log.Default())
+//	  logger.Println("hello")
+//	  // Output: AWS-XRAY-TRACE-ID: 1-5759e988-bd862e3fe1be46a994272793@53995c3f42cd8ad8 2009/11/10 23:00:00 hello
+//	}
+package tracelog
+
+import (
+	"context"
+	"log"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+// TraceIDKey is the key of the trace ID in the logs.
+const TraceIDKey = "AWS-XRAY-TRACE-ID"
+
+// TraceID returns the trace ID associated with ctx in the "trace-id@segment-id" format.
+// If ctx has no segment, it returns only the trace ID.
+// If ctx has no trace ID, it returns an empty string.
+func TraceID(ctx context.Context) string {
+	traceID := xray.ContextTraceID(ctx)
+	if traceID == "" {
+		return ""
+	}
+	if seg := xray.ContextSegment(ctx); seg != nil {
+		return traceID + "@" + seg.ID()
+	}
+	return traceID
+}
+
+// Prefix returns the log prefix that contains the trace ID, e.g. "AWS-XRAY-TRACE-ID: 1-5759e988-bd862e3fe1be46a994272793@53995c3f42cd8ad8 ".
+// If ctx has no trace ID, it returns an empty string.
+func Prefix(ctx context.Context) string {
+	id := TraceID(ctx)
+	if id == "" {
+		return ""
+	}
+	return TraceIDKey + ": " + id + " "
+}
+
+// Logger returns a new logger that adds the trace ID associated with ctx into the prefix of l.
+// If ctx has no trace ID, it returns l as it is.
+func Logger(ctx context.Context, l *log.Logger) *log.Logger {
+	prefix := Prefix(ctx)
+	if prefix == "" {
+		return l
+	}
+	return log.New(l.Writer(), prefix+l.Prefix(), l.Flags())
+}
@@ -0,0 +1,46 @@
+package tracelog
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestTraceID(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	if got := TraceID(ctx); got != "" {
+		t.Errorf("want empty, got %q", got)
+	}
+
+	ctx, seg := xray.BeginSegment(ctx, "foobar")
+	defer seg.Close()
+	want := seg.TraceID() + "@" + seg.ID()
+	if got := TraceID(ctx); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	var buf bytes.Buffer
+	l := log.New(&buf, "[app] ", 0)
+
+	// no trace id
+	if got := Logger(ctx, l); got != l {
+		t.Error("want the same logger, but not")
+	}
+
+	ctx, seg := xray.BeginSegment(ctx, "foobar")
+	defer seg.Close()
+	Logger(ctx, l).Print("hello")
+	want := "AWS-XRAY-TRACE-ID: " + seg.TraceID() + "@" + seg.ID() + " [app] hello\n"
+	if got := buf.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,108 @@
+// Package xraytest provides utilities for testing the applications instrumented by AWS X-Ray.
+//
+//	ctx, td := xraytest.NewDaemon(nil)
+//	defer td.Close()
+//
+//	ctx, seg := xray.BeginSegment(ctx, "my-service")
+//	// do something
+//	seg.Close()
+//
+//	tree, err := td.RecvTrace(seg.TraceID())
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	if !xraytest.Has(tree, xraytest.Name("dynamodb"), xraytest.Fault()) {
+//		t.Error("dynamodb subsegment with fault is not found")
+//	}
+//	xraytest.AssertGolden(t, "testdata/my-service.golden.json", tree)
+package xraytest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// Daemon is the mock server of AWS X-Ray daemon.
+// It assembles the segment documents into the trace trees.
+type Daemon struct {
+	*xray.TestDaemon
+
+	mu sync.Mutex
+
+	// the segment documents that are received, but not returned yet.
+	pending map[string][]*schema.Segment
+	order   []string
+}
+
+// NewDaemon creates new Daemon. If handler is not nil, it serves the sampling API.
+func NewDaemon(handler http.Handler) (context.Context, *Daemon) {
+	ctx, td := xray.NewTestDaemon(handler)
+	return ctx, &Daemon{
+		TestDaemon: td,
+		pending:    make(map[string][]*schema.Segment),
+	}
+}
+
+// RecvTrace receives the segment documents of the trace, and returns the trace tree.
+// If traceID is empty, the trace that is received first is returned.
+// The documents of other traces are kept for the following calls.
+//
+// RecvTrace returns when the root segment is completed.
+// The subsegments that are completed after the root segment may not be contained.
+func (d *Daemon) RecvTrace(traceID string) (*schema.Segment, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if traceID == "" && len(d.order) > 0 {
+		traceID = d.order[0]
+	}
+	if traceID != "" {
+		if tree, err := Tree(d.pending[traceID]); err == nil && isComplete(tree) {
+			d.remove(traceID)
+			return tree, nil
+		}
+	}
+
+	for {
+		seg, err := d.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("xraytest: failed to receive the trace %q: %w", traceID, err)
+		}
+		id := seg.TraceID
+		if _, ok := d.pending[id]; !ok {
+			d.order = append(d.order, id)
+		}
+		d.pending[id] = append(d.pending[id], seg)
+		if traceID == "" {
+			traceID = id
+		}
+		if id != traceID {
+			continue
+		}
+		if tree, err := Tree(d.pending[traceID]); err == nil && isComplete(tree) {
+			d.remove(traceID)
+			return tree, nil
+		}
+	}
+}
+
+func (d *Daemon) remove(traceID string) {
+	delete(d.pending, traceID)
+	for i, id := range d.order {
+		if id == traceID {
+			d.order = append(d.order[:i], d.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func isComplete(tree *schema.Segment) bool {
+	// the SDK injects the service information only into the root segments.
+	// it distinguishes the root from the subsegments whose parent is not received yet.
+	return tree.Service != nil && !tree.InProgress
+}
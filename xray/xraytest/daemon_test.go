@@ -0,0 +1,57 @@
+package xraytest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestDaemon_RecvTrace(t *testing.T) {
+	ctx, td := NewDaemon(nil)
+	defer td.Close()
+
+	ctx1, seg1 := xray.BeginSegment(ctx, "first")
+	_, seg2 := xray.BeginSegment(ctx, "second")
+
+	// the subsegments are streamed separately.
+	for i := 0; i < 30; i++ {
+		_, sub := xray.BeginSubsegment(ctx1, fmt.Sprintf("sub%02d", i))
+		sub.Close()
+	}
+	seg2.Close()
+	seg1.Close()
+
+	tree, err := td.RecvTrace(seg1.TraceID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Name != "first" {
+		t.Errorf("want %q, got %q", "first", tree.Name)
+	}
+	if len(tree.Subsegments) != 30 {
+		t.Fatalf("want 30 subsegments, got %d", len(tree.Subsegments))
+	}
+	for i, sub := range tree.Subsegments {
+		if want := fmt.Sprintf("sub%02d", i); sub.Name != want {
+			t.Errorf("want %q, got %q", want, sub.Name)
+		}
+		if sub.TraceID != "" || sub.ParentID != "" || sub.Type != "" {
+			t.Errorf("%s is not nested: %#v", sub.Name, sub)
+		}
+	}
+
+	// the second trace is buffered.
+	tree, err = td.RecvTrace("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Name != "second" {
+		t.Errorf("want %q, got %q", "second", tree.Name)
+	}
+
+	// no more traces.
+	if _, err := td.RecvTrace(""); err == nil {
+		t.Error("want error, got nil")
+	}
+}
@@ -0,0 +1,111 @@
+package xraytest
+
+import (
+	"reflect"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// Matcher reports whether the segment matches the condition.
+type Matcher func(seg *schema.Segment) bool
+
+// Name returns a matcher that matches the segments named name.
+func Name(name string) Matcher {
+	return func(seg *schema.Segment) bool {
+		return seg.Name == name
+	}
+}
+
+// Namespace returns a matcher that matches the segments in the namespace.
+func Namespace(namespace string) Matcher {
+	return func(seg *schema.Segment) bool {
+		return seg.Namespace == namespace
+	}
+}
+
+// Fault returns a matcher that matches the segments with the fault flag.
+func Fault() Matcher {
+	return func(seg *schema.Segment) bool {
+		return seg.Fault
+	}
+}
+
+// Error returns a matcher that matches the segments with the error flag.
+func Error() Matcher {
+	return func(seg *schema.Segment) bool {
+		return seg.Error
+	}
+}
+
+// Throttle returns a matcher that matches the segments with the throttle flag.
+func Throttle() Matcher {
+	return func(seg *schema.Segment) bool {
+		return seg.Throttle
+	}
+}
+
+// Annotation returns a matcher that matches the segments that have the annotation.
+// The numbers are compared as float64, because they are decoded so from JSON.
+func Annotation(key string, value interface{}) Matcher {
+	want := normalizeNumber(value)
+	return func(seg *schema.Segment) bool {
+		got, ok := seg.Annotations[key]
+		return ok && reflect.DeepEqual(normalizeNumber(got), want)
+	}
+}
+
+func normalizeNumber(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return v
+}
+
+func match(seg *schema.Segment, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m(seg) {
+			return false
+		}
+	}
+	return true
+}
+
+// FindAll returns all the segments in the tree that match all the matchers, in depth-first order.
+// The root itself is also tested.
+func FindAll(root *schema.Segment, matchers ...Matcher) []*schema.Segment {
+	var ret []*schema.Segment
+	var walk func(seg *schema.Segment)
+	walk = func(seg *schema.Segment) {
+		if match(seg, matchers) {
+			ret = append(ret, seg)
+		}
+		for _, sub := range seg.Subsegments {
+			walk(sub)
+		}
+	}
+	if root != nil {
+		walk(root)
+	}
+	return ret
+}
+
+// Find returns the first segment in the tree that matches all the matchers.
+// It returns nil if no segment matches.
+func Find(root *schema.Segment, matchers ...Matcher) *schema.Segment {
+	if all := FindAll(root, matchers...); len(all) > 0 {
+		return all[0]
+	}
+	return nil
+}
+
+// Has reports whether the tree has a segment that matches all the matchers.
+func Has(root *schema.Segment, matchers ...Matcher) bool {
+	return Find(root, matchers...) != nil
+}
@@ -0,0 +1,51 @@
+package xraytest
+
+import (
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func TestMatcher(t *testing.T) {
+	tree := &schema.Segment{
+		Name: "root",
+		Subsegments: []*schema.Segment{
+			{
+				Name:      "dynamodb",
+				Namespace: "aws",
+				Fault:     true,
+			},
+			{
+				Name:     "remote",
+				Error:    true,
+				Throttle: true,
+				Annotations: map[string]interface{}{
+					"count": float64(42),
+					"route": "/foo",
+				},
+			},
+		},
+	}
+
+	if !Has(tree, Name("root")) {
+		t.Error("want root, but not found")
+	}
+	if !Has(tree, Name("dynamodb"), Namespace("aws"), Fault()) {
+		t.Error("want dynamodb with fault, but not found")
+	}
+	if Has(tree, Name("dynamodb"), Error()) {
+		t.Error("want dynamodb without error, but found")
+	}
+	if !Has(tree, Error(), Throttle(), Annotation("count", 42), Annotation("route", "/foo")) {
+		t.Error("want remote, but not found")
+	}
+	if Has(tree, Annotation("count", "42")) {
+		t.Error("want no match, but found")
+	}
+	if seg := Find(tree, Fault()); seg == nil || seg.Name != "dynamodb" {
+		t.Errorf("unexpected segment: %v", seg)
+	}
+	if got := len(FindAll(tree)); got != 3 {
+		t.Errorf("want 3, got %d", got)
+	}
+}
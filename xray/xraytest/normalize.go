@@ -0,0 +1,108 @@
+package xraytest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// UpdateGoldenEnv is the name of the environment value.
+// If it is set, AssertGolden updates the golden files instead of comparing.
+const UpdateGoldenEnv = "XRAYTEST_UPDATE_GOLDEN"
+
+// Normalize returns a copy of seg with the fields that change every execution cleared.
+// They are the IDs, the times, the service information, aws.xray,
+// the working directory and the IDs and the stacks of the exceptions.
+func Normalize(seg *schema.Segment) *schema.Segment {
+	if seg == nil {
+		return nil
+	}
+	out := *seg
+	out.ID = ""
+	out.TraceID = ""
+	out.ParentID = ""
+	out.StartTime = 0
+	out.EndTime = 0
+	out.Service = nil
+	out.PrecursorIDs = nil
+	if out.AWS != nil {
+		aws := make(schema.AWS, len(out.AWS))
+		for k, v := range out.AWS {
+			if k != "xray" {
+				aws[k] = v
+			}
+		}
+		out.AWS = aws
+		if len(out.AWS) == 0 {
+			out.AWS = nil
+		}
+	}
+	if out.Cause != nil {
+		cause := *out.Cause
+		cause.WorkingDirectory = ""
+		cause.Exceptions = make([]schema.Exception, len(out.Cause.Exceptions))
+		for i, e := range out.Cause.Exceptions {
+			e.ID = ""
+			e.Stack = nil
+			cause.Exceptions[i] = e
+		}
+		out.Cause = &cause
+	}
+	out.Subsegments = nil
+	for _, sub := range seg.Subsegments {
+		out.Subsegments = append(out.Subsegments, Normalize(sub))
+	}
+	return &out
+}
+
+// IgnoreVariableFields is a cmp.Option that compares the segments after normalization.
+var IgnoreVariableFields = cmp.Transformer("Normalize", Normalize)
+
+// AssertGolden compares the normalized seg with the golden file.
+// If the XRAYTEST_UPDATE_GOLDEN environment value is set, it updates the golden file instead.
+func AssertGolden(t testing.TB, filename string, seg *schema.Segment) {
+	t.Helper()
+	got := Normalize(seg)
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		data, err := json.MarshalIndent(got, "", "  ")
+		if err != nil {
+			t.Fatalf("xraytest: failed to encode the segment: %v", err)
+		}
+		data = append(data, '\n')
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("xraytest: failed to create the directory: %v", err)
+		}
+		if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+			t.Fatalf("xraytest: failed to update the golden file: %v", err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("xraytest: failed to read the golden file: %v", err)
+	}
+	var want *schema.Segment
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatalf("xraytest: failed to decode the golden file: %v", err)
+	}
+
+	// compare them in the decoded form, because the types of the values in maps are changed by JSON.
+	data, err = json.Marshal(got)
+	if err != nil {
+		t.Fatalf("xraytest: failed to encode the segment: %v", err)
+	}
+	var decoded *schema.Segment
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("xraytest: failed to decode the segment: %v", err)
+	}
+	if diff := cmp.Diff(want, decoded); diff != "" {
+		t.Errorf("xraytest: the segment doesn't match the golden file %s (-want +got):\n%s", filename, diff)
+	}
+}
@@ -0,0 +1,91 @@
+package xraytest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func TestNormalize(t *testing.T) {
+	seg := &schema.Segment{
+		Name:      "root",
+		ID:        "0000000000000001",
+		TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		StartTime: 1000000000,
+		EndTime:   1000000001,
+		Service:   xray.ServiceData,
+		AWS: schema.AWS{
+			"xray":       map[string]interface{}{"sdk": "X-Ray for Go"},
+			"account_id": "123456789012",
+		},
+		Cause: &schema.Cause{
+			WorkingDirectory: "/",
+			Exceptions: []schema.Exception{
+				{
+					ID:      "0000000000000002",
+					Message: "some error",
+					Stack: []schema.StackFrame{
+						{Path: "main.go", Line: 42, Label: "main.main"},
+					},
+				},
+			},
+		},
+		Subsegments: []*schema.Segment{
+			{
+				Name:      "sub",
+				ID:        "0000000000000003",
+				StartTime: 1000000000,
+				EndTime:   1000000001,
+				AWS: schema.AWS{
+					"xray": map[string]interface{}{"sdk": "X-Ray for Go"},
+				},
+			},
+		},
+	}
+	want := &schema.Segment{
+		Name: "root",
+		AWS: schema.AWS{
+			"account_id": "123456789012",
+		},
+		Cause: &schema.Cause{
+			Exceptions: []schema.Exception{
+				{Message: "some error"},
+			},
+		},
+		Subsegments: []*schema.Segment{
+			{
+				Name: "sub",
+			},
+		},
+	}
+	got := Normalize(seg)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// the original segment is not modified.
+	if seg.ID == "" || seg.AWS["xray"] == nil || seg.Cause.Exceptions[0].ID == "" {
+		t.Error("the original segment is modified")
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	ctx, td := NewDaemon(nil)
+	defer td.Close()
+
+	ctx, seg := xray.BeginSegment(ctx, "golden")
+	xray.AddAnnotationInt64(ctx, "count", 42)
+	_, sub := xray.BeginSubsegment(ctx, "sub")
+	sub.AddError(errors.New("some error"))
+	sub.Close()
+	seg.Close()
+
+	tree, err := td.RecvTrace(seg.TraceID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertGolden(t, "testdata/golden.golden.json", tree)
+}
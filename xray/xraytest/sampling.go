@@ -0,0 +1,40 @@
+package xraytest
+
+import (
+	"github.com/shogo82148/aws-xray-yasdk-go/internal/xrayserver"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+// SamplingRule is a sampling rule served by SamplingServer.
+// https://docs.aws.amazon.com/xray/latest/api/API_SamplingRule.html
+type SamplingRule = xrayserver.SamplingRule
+
+// DefaultSamplingRule is the default rule of AWS X-Ray.
+var DefaultSamplingRule = xrayserver.DefaultSamplingRule
+
+// SamplingTarget is the quota of a rule served by SamplingServer.
+type SamplingTarget = xrayserver.SamplingTarget
+
+// SamplingStatistics is the statistics received by SamplingServer.
+// https://docs.aws.amazon.com/xray/latest/api/API_SamplingStatisticsDocument.html
+type SamplingStatistics = xrayserver.SamplingStatistics
+
+// SamplingServer is a fake of the centralized sampling API served by AWS X-Ray daemon.
+// It serves GetSamplingRules and GetSamplingTargets.
+//
+//	server := xraytest.NewSamplingServer()
+//	server.SetRules(xraytest.SamplingRule{RuleName: "my-rule", ...}, xraytest.DefaultSamplingRule)
+//	ctx, td := xraytest.NewDaemon(server)
+type SamplingServer = xrayserver.SamplingServer
+
+// NewSamplingServer returns a new SamplingServer that serves DefaultSamplingRule.
+func NewSamplingServer() *SamplingServer {
+	return xrayserver.NewSamplingServer()
+}
+
+// SamplingRulesFromManifest converts the local sampling rules into the centralized sampling rules.
+// The rules are named by their description, or "rule-N" if the description is empty.
+// The empty patterns match any values.
+func SamplingRulesFromManifest(manifest *sampling.Manifest) []SamplingRule {
+	return xrayserver.SamplingRulesFromManifest(manifest)
+}
@@ -0,0 +1,14 @@
+package xraytest
+
+import (
+	"github.com/shogo82148/aws-xray-yasdk-go/internal/xrayserver"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// Tree reconstructs the trace tree from the segment documents of a trace.
+// The documents may be streamed separately, they are linked by their parent_id.
+// If the same segment is found more than once, the completed one is used.
+// The subsegments are sorted by their start time.
+func Tree(docs []*schema.Segment) (*schema.Segment, error) {
+	return xrayserver.Tree(docs)
+}
@@ -0,0 +1,288 @@
+package xrayviewer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+type listRow struct {
+	TraceID  string
+	URL      string
+	Name     string
+	Start    string
+	Duration string
+	Status   string
+	HTTP     string
+	Segments int
+}
+
+func newListRow(tree *schema.Segment) listRow {
+	return listRow{
+		TraceID:  tree.TraceID,
+		URL:      "trace?id=" + url.QueryEscape(tree.TraceID),
+		Name:     tree.Name,
+		Start:    epochToTime(tree.StartTime).Format("2006-01-02 15:04:05.000"),
+		Duration: formatDuration(tree),
+		Status:   status(tree),
+		HTTP:     httpSummary(tree),
+		Segments: countSegments(tree),
+	}
+}
+
+type traceView struct {
+	TraceID  string
+	Name     string
+	Start    string
+	Duration string
+	Rows     []spanRow
+}
+
+type spanRow struct {
+	Indent     int
+	Name       string
+	Namespace  string
+	Offset     string
+	Duration   string
+	Left       float64
+	Width      float64
+	Status     string
+	HTTP       string
+	Details    []detail
+	Exceptions []schema.Exception
+}
+
+type detail struct {
+	Name  string
+	Value string
+}
+
+func newTraceView(tree *schema.Segment) *traceView {
+	start, end := tree.StartTime, traceEnd(tree)
+	total := end - start
+	view := &traceView{
+		TraceID:  tree.TraceID,
+		Name:     tree.Name,
+		Start:    epochToTime(start).Format("2006-01-02 15:04:05.000"),
+		Duration: fmt.Sprintf("%.3fms", total*1000),
+	}
+
+	var walk func(seg *schema.Segment, depth int)
+	walk = func(seg *schema.Segment, depth int) {
+		segEnd := seg.EndTime
+		if seg.InProgress {
+			segEnd = end
+		}
+		row := spanRow{
+			Indent:    depth * 16,
+			Name:      seg.Name,
+			Namespace: seg.Namespace,
+			Offset:    fmt.Sprintf("+%.3fms", (seg.StartTime-start)*1000),
+			Duration:  formatDuration(seg),
+			Status:    status(seg),
+			HTTP:      httpSummary(seg),
+		}
+		if total > 0 {
+			row.Left = (seg.StartTime - start) / total * 100
+			row.Width = (segEnd - seg.StartTime) / total * 100
+		}
+		if row.Width < 0.5 {
+			// keep the bar visible.
+			row.Width = 0.5
+		}
+		row.Details = appendDetail(row.Details, "http", seg.HTTP)
+		row.Details = appendDetail(row.Details, "sql", seg.SQL)
+		row.Details = appendDetail(row.Details, "aws", seg.AWS)
+		row.Details = appendDetail(row.Details, "annotations", seg.Annotations)
+		row.Details = appendDetail(row.Details, "metadata", seg.Metadata)
+		if seg.Cause != nil {
+			row.Exceptions = seg.Cause.Exceptions
+		}
+		view.Rows = append(view.Rows, row)
+		for _, sub := range seg.Subsegments {
+			walk(sub, depth+1)
+		}
+	}
+	walk(tree, 0)
+	return view
+}
+
+func appendDetail(details []detail, name string, v interface{}) []detail {
+	switch v := v.(type) {
+	case *schema.HTTP:
+		if v == nil {
+			return details
+		}
+	case *schema.SQL:
+		if v == nil {
+			return details
+		}
+	case schema.AWS:
+		if len(v) == 0 {
+			return details
+		}
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return details
+		}
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		data = []byte(err.Error())
+	}
+	return append(details, detail{Name: name, Value: string(data)})
+}
+
+// traceEnd returns the end time of the trace.
+func traceEnd(seg *schema.Segment) float64 {
+	end := seg.EndTime
+	if end < seg.StartTime {
+		end = seg.StartTime
+	}
+	for _, sub := range seg.Subsegments {
+		if e := traceEnd(sub); e > end {
+			end = e
+		}
+	}
+	return end
+}
+
+func epochToTime(epoch float64) time.Time {
+	sec := int64(epoch)
+	nsec := int64((epoch - float64(sec)) * 1e9)
+	return time.Unix(sec, nsec)
+}
+
+func formatDuration(seg *schema.Segment) string {
+	if seg.InProgress {
+		return "in progress"
+	}
+	return fmt.Sprintf("%.3fms", (seg.EndTime-seg.StartTime)*1000)
+}
+
+func status(seg *schema.Segment) string {
+	switch {
+	case seg.Fault:
+		return "fault"
+	case seg.Throttle:
+		return "throttle"
+	case seg.Error:
+		return "error"
+	}
+	return "ok"
+}
+
+func httpSummary(seg *schema.Segment) string {
+	if seg.HTTP == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if req := seg.HTTP.Request; req != nil {
+		buf.WriteString(req.Method)
+		if req.URL != "" {
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(req.URL)
+		}
+	}
+	if resp := seg.HTTP.Response; resp != nil && resp.Status != 0 {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprint(&buf, resp.Status)
+	}
+	return buf.String()
+}
+
+func countSegments(seg *schema.Segment) int {
+	n := 1
+	for _, sub := range seg.Subsegments {
+		n += countSegments(sub)
+	}
+	return n
+}
+
+func render(w http.ResponseWriter, tmpl *template.Template, data interface{}) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+const style = `
+<style>
+body { font-family: sans-serif; font-size: 14px; margin: 1em 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; vertical-align: top; }
+.ok { color: #2e7d32; }
+.error { color: #ef6c00; }
+.throttle { color: #6a1b9a; }
+.fault { color: #c62828; }
+.timeline { position: relative; width: 400px; height: 14px; background: #f5f5f5; }
+.bar { position: absolute; height: 14px; background: #64b5f6; }
+.bar.error { background: #ffb74d; }
+.bar.throttle { background: #ba68c8; }
+.bar.fault { background: #e57373; }
+pre { margin: 4px 0; background: #fafafa; padding: 4px; }
+</style>
+`
+
+var listTemplate = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>AWS X-Ray Traces</title>` + style + `</head>
+<body>
+<h1>Traces</h1>
+<form method="get" action="">
+<input type="text" name="annotation" value="{{.Query}}" placeholder="key=value" size="40">
+<input type="submit" value="Search by annotation">
+</form>
+<table>
+<tr><th>Start</th><th>Name</th><th>Duration</th><th>Status</th><th>HTTP</th><th>Segments</th><th>Trace ID</th></tr>
+{{range .Rows}}<tr>
+<td>{{.Start}}</td>
+<td><a href="{{.URL}}">{{.Name}}</a></td>
+<td>{{.Duration}}</td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{.HTTP}}</td>
+<td>{{.Segments}}</td>
+<td><a href="{{.URL}}">{{.TraceID}}</a></td>
+</tr>
+{{else}}<tr><td colspan="7">no traces</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+var traceTemplate = template.Must(template.New("trace").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.TraceID}} - AWS X-Ray Traces</title>` + style + `</head>
+<body>
+<p><a href="./">&laquo; Traces</a></p>
+<h1>{{.Name}}</h1>
+<p>Trace ID: {{.TraceID}}<br>Start: {{.Start}}<br>Duration: {{.Duration}}</p>
+<table>
+<tr><th>Name</th><th>Timeline</th><th>Offset</th><th>Duration</th><th>Status</th><th>Details</th></tr>
+{{range .Rows}}<tr>
+<td><span style="padding-left: {{.Indent}}px">{{.Name}}</span>{{if .Namespace}} <small>({{.Namespace}})</small>{{end}}</td>
+<td><div class="timeline"><div class="bar {{.Status}}" style="left: {{printf "%.3f" .Left}}%; width: {{printf "%.3f" .Width}}%"></div></div></td>
+<td>{{.Offset}}</td>
+<td>{{.Duration}}</td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{if .HTTP}}{{.HTTP}}{{end}}
+{{range .Exceptions}}<div class="fault">{{.Type}}: {{.Message}}{{range .Stack}}<br><small>{{.Label}} {{.Path}}:{{.Line}}</small>{{end}}</div>{{end}}
+{{range .Details}}<details><summary>{{.Name}}</summary><pre>{{.Value}}</pre></details>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
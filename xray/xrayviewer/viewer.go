@@ -0,0 +1,172 @@
+// Package xrayviewer provides a web UI for browsing the traces locally, without AWS access.
+//
+//	viewer := xrayviewer.New(100)
+//	viewer.Add(tree) // e.g. a trace tree assembled by xraytest.Daemon.RecvTrace
+//	http.ListenAndServe("127.0.0.1:2001", viewer)
+package xrayviewer
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// DefaultSize is the default number of traces kept by Viewer.
+const DefaultSize = 100
+
+// Viewer is an http.Handler that renders the recent traces.
+// It serves the list of traces on "/", and the waterfall view of a trace on "/trace?id=<trace id>".
+// Use http.StripPrefix to mount it on a sub path.
+type Viewer struct {
+	mu     sync.RWMutex
+	traces []*schema.Segment
+	next   int
+	full   bool
+}
+
+// New returns a new Viewer that keeps the recent size traces.
+// If size is zero or negative, DefaultSize is used.
+func New(size int) *Viewer {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Viewer{
+		traces: make([]*schema.Segment, size),
+	}
+}
+
+// Add adds the trace tree. If the buffer is full, the oldest trace is discarded.
+// The viewer doesn't modify the tree, and the caller must not modify it after adding.
+func (v *Viewer) Add(tree *schema.Segment) {
+	if tree == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.traces[v.next] = tree
+	v.next++
+	if v.next == len(v.traces) {
+		v.next = 0
+		v.full = true
+	}
+}
+
+// Traces returns the traces in the buffer, newest first.
+func (v *Viewer) Traces() []*schema.Segment {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	n := v.next
+	if v.full {
+		n = len(v.traces)
+	}
+	ret := make([]*schema.Segment, 0, n)
+	for i := 0; i < n; i++ {
+		idx := (v.next - 1 - i + len(v.traces)) % len(v.traces)
+		ret = append(ret, v.traces[idx])
+	}
+	return ret
+}
+
+// Find returns the newest trace that has the trace id.
+func (v *Viewer) Find(traceID string) *schema.Segment {
+	for _, tree := range v.Traces() {
+		if tree.TraceID == traceID {
+			return tree
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (v *Viewer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, "/") {
+	case "":
+		v.serveList(w, r)
+	case "trace":
+		v.serveTrace(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (v *Viewer) serveList(w http.ResponseWriter, r *http.Request) {
+	// the conditions are separated by spaces.
+	var queries []string
+	for _, q := range r.URL.Query()["annotation"] {
+		queries = append(queries, strings.Fields(q)...)
+	}
+	conds := make([]annotationCond, 0, len(queries))
+	for _, q := range queries {
+		conds = append(conds, parseAnnotationCond(q))
+	}
+
+	var rows []listRow
+	for _, tree := range v.Traces() {
+		if !matchTree(tree, conds) {
+			continue
+		}
+		rows = append(rows, newListRow(tree))
+	}
+	render(w, listTemplate, map[string]interface{}{
+		"Query": strings.Join(queries, " "),
+		"Rows":  rows,
+	})
+}
+
+func (v *Viewer) serveTrace(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	tree := v.Find(id)
+	if tree == nil {
+		http.NotFound(w, r)
+		return
+	}
+	render(w, traceTemplate, newTraceView(tree))
+}
+
+// annotationCond is a condition of the search.
+// "key=value" matches the segments that have the annotation, "key" matches the segments that have the key.
+type annotationCond struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+func parseAnnotationCond(q string) annotationCond {
+	if idx := strings.IndexByte(q, '='); idx >= 0 {
+		return annotationCond{key: q[:idx], value: q[idx+1:], hasValue: true}
+	}
+	return annotationCond{key: q}
+}
+
+func (c annotationCond) match(seg *schema.Segment) bool {
+	v, ok := seg.Annotations[c.key]
+	if !ok {
+		return false
+	}
+	return !c.hasValue || fmt.Sprint(v) == c.value
+}
+
+// matchTree reports whether each condition matches some segment in the tree.
+func matchTree(tree *schema.Segment, conds []annotationCond) bool {
+	for _, c := range conds {
+		if !matchAny(tree, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(seg *schema.Segment, c annotationCond) bool {
+	if c.match(seg) {
+		return true
+	}
+	for _, sub := range seg.Subsegments {
+		if matchAny(sub, c) {
+			return true
+		}
+	}
+	return false
+}
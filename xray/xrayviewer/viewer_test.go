@@ -0,0 +1,169 @@
+package xrayviewer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func newTrace(i int) *schema.Segment {
+	return &schema.Segment{
+		Name:      fmt.Sprintf("service-%d", i),
+		TraceID:   fmt.Sprintf("1-5e645f3e-%024d", i),
+		StartTime: 1000000000,
+		EndTime:   1000000001,
+		Annotations: map[string]interface{}{
+			"index": float64(i),
+		},
+	}
+}
+
+func TestViewer_RingBuffer(t *testing.T) {
+	v := New(3)
+	for i := 0; i < 5; i++ {
+		v.Add(newTrace(i))
+	}
+	traces := v.Traces()
+	if len(traces) != 3 {
+		t.Fatalf("want 3 traces, got %d", len(traces))
+	}
+	for i, want := range []string{"service-4", "service-3", "service-2"} {
+		if traces[i].Name != want {
+			t.Errorf("want %q, got %q", want, traces[i].Name)
+		}
+	}
+	if v.Find(newTrace(1).TraceID) != nil {
+		t.Error("the oldest trace is not discarded")
+	}
+	if v.Find(newTrace(4).TraceID) == nil {
+		t.Error("the newest trace is not found")
+	}
+}
+
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	h.ServeHTTP(rec, req)
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, string(body)
+}
+
+func TestViewer_List(t *testing.T) {
+	v := New(10)
+	for i := 0; i < 3; i++ {
+		v.Add(newTrace(i))
+	}
+	tree := newTrace(3)
+	tree.Subsegments = []*schema.Segment{
+		{
+			Name:        "sub",
+			StartTime:   1000000000,
+			EndTime:     1000000000.5,
+			Annotations: map[string]interface{}{"route": "/foo"},
+		},
+	}
+	v.Add(tree)
+
+	code, body := get(t, v, "/")
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", code)
+	}
+	for i := 0; i < 4; i++ {
+		if !strings.Contains(body, fmt.Sprintf("service-%d", i)) {
+			t.Errorf("service-%d is not found", i)
+		}
+	}
+
+	// search by annotation
+	_, body = get(t, v, "/?annotation=index%3D1")
+	if !strings.Contains(body, "service-1") || strings.Contains(body, "service-2") {
+		t.Errorf("unexpected search result: %s", body)
+	}
+	_, body = get(t, v, "/?annotation=route%3D%2Ffoo+index")
+	if !strings.Contains(body, "service-3") || strings.Contains(body, "service-1") {
+		t.Errorf("unexpected search result: %s", body)
+	}
+	_, body = get(t, v, "/?annotation=unknown")
+	if !strings.Contains(body, "no traces") {
+		t.Errorf("unexpected search result: %s", body)
+	}
+}
+
+func TestViewer_Trace(t *testing.T) {
+	v := New(10)
+	tree := &schema.Segment{
+		Name:      "my-service",
+		TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		StartTime: 1000000000,
+		EndTime:   1000000001,
+		HTTP: &schema.HTTP{
+			Request:  &schema.HTTPRequest{Method: "GET", URL: "http://example.com/foo"},
+			Response: &schema.HTTPResponse{Status: 500},
+		},
+		Fault:       true,
+		Annotations: map[string]interface{}{"route": "/foo"},
+		Metadata:    map[string]interface{}{"default": map[string]interface{}{"key": "<value>"}},
+		Subsegments: []*schema.Segment{
+			{
+				Name:      "db",
+				Namespace: "remote",
+				StartTime: 1000000000.25,
+				EndTime:   1000000000.75,
+				SQL:       &schema.SQL{SanitizedQuery: "SELECT 1"},
+				Cause: &schema.Cause{
+					Exceptions: []schema.Exception{
+						{Type: "*errors.errorString", Message: errors.New("boom").Error()},
+					},
+				},
+			},
+			{
+				Name:      "dynamodb",
+				StartTime: 1000000000.5,
+				EndTime:   1000000000.6,
+				AWS:       schema.AWS{"operation": "GetItem"},
+			},
+		},
+	}
+	v.Add(tree)
+
+	code, body := get(t, v, "/trace?id=1-5e645f3e-1dfad076a177c5ccc5de12f5")
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", code)
+	}
+	for _, want := range []string{
+		"my-service",
+		"GET http://example.com/foo 500",
+		"1000.000ms",
+		"&#34;route&#34;: &#34;/foo&#34;",
+		`\u003cvalue\u003e`,
+		"db",
+		"SELECT 1",
+		"boom",
+		"&#43;250.000ms",
+		"left: 25.000%; width: 50.000%",
+		"GetItem",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("%q is not found in:\n%s", want, body)
+		}
+	}
+
+	code, _ = get(t, v, "/trace?id=unknown")
+	if code != http.StatusNotFound {
+		t.Errorf("want %d, got %d", http.StatusNotFound, code)
+	}
+	code, _ = get(t, v, "/unknown")
+	if code != http.StatusNotFound {
+		t.Errorf("want %d, got %d", http.StatusNotFound, code)
+	}
+}
@@ -0,0 +1,257 @@
+package zipkin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/xraylog"
+)
+
+const (
+	defaultInterval     = time.Second
+	defaultBatchSize    = 100
+	defaultMaxQueueSize = 10000
+)
+
+// Option is an option for NewEmitter.
+type Option func(*config)
+
+type config struct {
+	client       *http.Client
+	serviceName  string
+	interval     time.Duration
+	batchSize    int
+	maxQueueSize int
+}
+
+// WithHTTPClient configures the HTTP client for sending spans.
+// By default, http.DefaultClient is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(cfg *config) {
+		cfg.client = client
+	}
+}
+
+// WithServiceName configures the service name for the subsegments that are streamed separately from their root segments.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithInterval configures the interval of sending spans. The default is one second.
+func WithInterval(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.interval = interval
+	}
+}
+
+// WithBatchSize configures the maximum number of spans in a request. The default is 100.
+func WithBatchSize(size int) Option {
+	return func(cfg *config) {
+		cfg.batchSize = size
+	}
+}
+
+// WithMaxQueueSize configures the maximum number of spans waiting to be sent. The default is 10000.
+// If the queue is full, the oldest spans are dropped.
+func WithMaxQueueSize(size int) Option {
+	return func(cfg *config) {
+		cfg.maxQueueSize = size
+	}
+}
+
+// Emitter sends the segments to the Zipkin collector in background.
+// It implements xray.Emitter and xray.DropCounter.
+type Emitter struct {
+	url string
+	cfg config
+
+	mu      sync.Mutex
+	spans   []*Span
+	dropped uint64
+	closed  bool
+	muFlush sync.Mutex
+
+	ch        chan struct{}
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewEmitter returns a new Emitter that sends the spans to url,
+// e.g. "http://localhost:9411/api/v2/spans".
+func NewEmitter(url string, opts ...Option) *Emitter {
+	cfg := config{
+		client:       http.DefaultClient,
+		interval:     defaultInterval,
+		batchSize:    defaultBatchSize,
+		maxQueueSize: defaultMaxQueueSize,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.interval <= 0 {
+		panic(fmt.Sprintf("xray/zipkin: invalid interval: %s", cfg.interval))
+	}
+	if cfg.batchSize <= 0 {
+		panic(fmt.Sprintf("xray/zipkin: invalid batch size: %d", cfg.batchSize))
+	}
+	if cfg.maxQueueSize <= 0 {
+		panic(fmt.Sprintf("xray/zipkin: invalid max queue size: %d", cfg.maxQueueSize))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Emitter{
+		url:    url,
+		cfg:    cfg,
+		ch:     make(chan struct{}, 1),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go e.run(ctx)
+	return e
+}
+
+// Emit converts the segment document into spans, and queues them.
+// The segments in progress are skipped.
+func (e *Emitter) Emit(ctx context.Context, doc *schema.Segment) error {
+	spans, err := Convert(doc, e.cfg.serviceName)
+	if err != nil {
+		return err
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return errors.New("xray/zipkin: the emitter is already closed")
+	}
+	e.spans = append(e.spans, spans...)
+	e.trim()
+	if len(e.spans) >= e.cfg.batchSize {
+		select {
+		case e.ch <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// trim drops the oldest spans that exceed the max queue size.
+// e.mu must be held.
+func (e *Emitter) trim() {
+	if over := len(e.spans) - e.cfg.maxQueueSize; over > 0 {
+		e.dropped += uint64(over)
+		e.spans = append([]*Span(nil), e.spans[over:]...)
+	}
+}
+
+// Dropped returns the number of the dropped spans.
+// The spans are dropped when the queue is full, or they can't be sent on Close.
+func (e *Emitter) Dropped() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dropped
+}
+
+func (e *Emitter) run(ctx context.Context) {
+	defer close(e.done)
+	ticker := time.NewTicker(e.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.ch:
+		}
+		if err := e.Flush(context.Background()); err != nil {
+			xraylog.ErrorAttrs(ctx, "xray/zipkin: failed to send spans", xraylog.Err(err), xraylog.String("url", e.url))
+		}
+	}
+}
+
+// Flush sends the queued spans.
+// If it fails, the spans that are not sent are queued again, and retried on the next flush.
+func (e *Emitter) Flush(ctx context.Context) error {
+	e.muFlush.Lock()
+	defer e.muFlush.Unlock()
+
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > e.cfg.batchSize {
+			n = e.cfg.batchSize
+		}
+		if err := e.send(ctx, spans[:n]); err != nil {
+			e.mu.Lock()
+			e.spans = append(spans, e.spans...)
+			e.trim()
+			e.mu.Unlock()
+			return err
+		}
+		spans = spans[n:]
+	}
+	return nil
+}
+
+func (e *Emitter) send(ctx context.Context, spans []*Span) error {
+	data, err := json.Marshal(spans)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("xray/zipkin: unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close stops sending in background, and sends the remaining spans.
+func (e *Emitter) Close() error {
+	var err error
+	e.closeOnce.Do(func() {
+		e.mu.Lock()
+		e.closed = true
+		e.mu.Unlock()
+		e.cancel()
+		<-e.done
+		err = e.Flush(context.Background())
+		if err != nil {
+			// there is no chance to send the remaining spans.
+			e.mu.Lock()
+			e.dropped += uint64(len(e.spans))
+			e.spans = nil
+			e.mu.Unlock()
+		}
+	})
+	return err
+}
@@ -0,0 +1,169 @@
+package zipkin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+var _ xray.Emitter = (*Emitter)(nil)
+
+type collector struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var spans []*Span
+	if err := json.NewDecoder(r.Body).Decode(&spans); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.spans = append(c.spans, spans...)
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestEmitter(t *testing.T) {
+	c := &collector{}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	emitter := NewEmitter(ts.URL+"/api/v2/spans", WithBatchSize(1))
+	client := xray.New(&xray.Config{
+		SamplingStrategy: sampling.NewAllStrategy(),
+		Emitter:          emitter,
+	})
+	ctx := xray.WithClient(context.Background(), client)
+
+	ctx, root := xray.BeginSegment(ctx, "my-service")
+	_, sub := xray.BeginSubsegment(ctx, "sub")
+	sub.Close()
+	root.Close()
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := emitter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(c.spans))
+	}
+	spans := map[string]*Span{}
+	for _, span := range c.spans {
+		spans[span.Name] = span
+	}
+	if spans["sub"] == nil || spans["my-service"] == nil {
+		t.Fatalf("unexpected spans: %v", c.spans)
+	}
+	if spans["sub"].ParentID != spans["my-service"].ID {
+		t.Errorf("want parent %s, got %s", spans["my-service"].ID, spans["sub"].ParentID)
+	}
+	if spans["sub"].LocalEndpoint.ServiceName != "my-service" {
+		t.Errorf("unexpected service name: %s", spans["sub"].LocalEndpoint.ServiceName)
+	}
+	if got := client.Stats().SegmentsEmitted; got != 1 {
+		t.Errorf("want %d, got %d", 1, got)
+	}
+}
+
+func TestEmitter_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	emitter := NewEmitter(ts.URL + "/api/v2/spans")
+	doc := `{"name":"foobar","id":"03babb4ba280be51","trace_id":"1-5e645f3e-1dfad076a177c5ccc5de12f5","start_time":1000000000,"end_time":1000000001}`
+	var seg schema.Segment
+	if err := json.Unmarshal([]byte(doc), &seg); err != nil {
+		t.Fatal(err)
+	}
+	if err := emitter.Emit(context.Background(), &seg); err != nil {
+		t.Fatal(err)
+	}
+	if err := emitter.Close(); err == nil {
+		t.Error("want error, got nil")
+	}
+	if got := emitter.Dropped(); got != 1 {
+		t.Errorf("want %d dropped span, got %d", 1, got)
+	}
+	if err := emitter.Emit(context.Background(), &seg); err == nil {
+		t.Error("want error after closed, got nil")
+	}
+}
+
+func TestEmitter_MaxQueueSize(t *testing.T) {
+	var mu sync.Mutex
+	fail := true
+	c := &collector{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		c.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	emitter := NewEmitter(ts.URL+"/api/v2/spans", WithInterval(time.Hour), WithMaxQueueSize(2))
+	client := xray.New(&xray.Config{
+		SamplingStrategy: sampling.NewAllStrategy(),
+		Emitter:          emitter,
+	})
+	ctx := xray.WithClient(context.Background(), client)
+	for _, name := range []string{"first", "second", "third"} {
+		_, root := xray.BeginSegment(ctx, name)
+		root.Close()
+	}
+
+	// the oldest span is dropped.
+	if got := client.Stats().EmitterDropped; got != 1 {
+		t.Errorf("want %d, got %d", 1, got)
+	}
+
+	// the spans are queued again after the failure.
+	if err := emitter.Flush(context.Background()); err == nil {
+		t.Error("want error, got nil")
+	}
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if err := emitter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	var names []string
+	for _, span := range c.spans {
+		names = append(names, span.Name)
+	}
+	c.mu.Unlock()
+	if diff := cmp.Diff([]string{"second", "third"}, names); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if got := emitter.Dropped(); got != 1 {
+		t.Errorf("want %d, got %d", 1, got)
+	}
+	client.Close()
+	emitter.Close()
+}
@@ -0,0 +1,214 @@
+// Package zipkin converts the segment documents of AWS X-Ray into Zipkin v2 spans,
+// and sends them to Zipkin compatible backends, e.g. Zipkin and Jaeger.
+//
+//	emitter := zipkin.NewEmitter("http://localhost:9411/api/v2/spans")
+//	defer emitter.Close()
+//	xray.Configure(&xray.Config{
+//		Emitter: emitter,
+//	})
+//
+// https://zipkin.io/zipkin-api/#/default/post_spans
+package zipkin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+// The kinds of spans.
+const (
+	KindClient = "CLIENT"
+	KindServer = "SERVER"
+)
+
+// Span is a span in Zipkin v2 format.
+type Span struct {
+	TraceID        string            `json:"traceId"`
+	ParentID       string            `json:"parentId,omitempty"`
+	ID             string            `json:"id"`
+	Kind           string            `json:"kind,omitempty"`
+	Name           string            `json:"name"`
+	Timestamp      int64             `json:"timestamp"`
+	Duration       int64             `json:"duration"`
+	LocalEndpoint  *Endpoint         `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *Endpoint         `json:"remoteEndpoint,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+// Endpoint is the network context of a node in the service graph.
+type Endpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+}
+
+// Convert converts the segment document into Zipkin spans.
+// The document may contain subsegments, they are converted into the child spans.
+// The segments in progress are skipped, but their completed subsegments are converted.
+//
+// The service name of the spans is the name of the root segment.
+// If the document is a subsegment streamed separately, serviceName is used instead.
+func Convert(doc *schema.Segment, serviceName string) ([]*Span, error) {
+	traceID, err := xray.TraceIDToW3C(doc.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("xray/zipkin: %w", err)
+	}
+
+	// the SDK injects the service information only into the root segments.
+	isRoot := doc.Service != nil || doc.Type != "subsegment"
+	if isRoot || serviceName == "" {
+		serviceName = doc.Name
+	}
+	local := &Endpoint{ServiceName: serviceName}
+
+	var spans []*Span
+	var walk func(seg *schema.Segment, parentID string, isRoot bool)
+	walk = func(seg *schema.Segment, parentID string, isRoot bool) {
+		if !seg.InProgress {
+			spans = append(spans, convertSegment(seg, traceID, parentID, isRoot, local))
+		}
+		for _, sub := range seg.Subsegments {
+			walk(sub, seg.ID, false)
+		}
+	}
+	walk(doc, doc.ParentID, isRoot)
+	return spans, nil
+}
+
+func convertSegment(seg *schema.Segment, traceID, parentID string, isRoot bool, local *Endpoint) *Span {
+	span := &Span{
+		TraceID:       traceID,
+		ParentID:      parentID,
+		ID:            seg.ID,
+		Name:          seg.Name,
+		Timestamp:     int64(seg.StartTime * 1e6),
+		Duration:      int64((seg.EndTime - seg.StartTime) * 1e6),
+		LocalEndpoint: local,
+		Tags:          tags(seg),
+	}
+	if span.Duration < 1 {
+		// zipkin requires positive durations.
+		span.Duration = 1
+	}
+	switch {
+	case isRoot:
+		if seg.HTTP != nil {
+			span.Kind = KindServer
+		}
+	case seg.Namespace == "remote" || seg.Namespace == "aws":
+		span.Kind = KindClient
+		span.RemoteEndpoint = &Endpoint{ServiceName: seg.Name}
+	}
+	return span
+}
+
+func tags(seg *schema.Segment) map[string]string {
+	tags := map[string]string{}
+	if seg.Namespace != "" {
+		tags["xray.namespace"] = seg.Namespace
+	}
+	if seg.User != "" {
+		tags["xray.user"] = seg.User
+	}
+	if seg.Origin != "" {
+		tags["xray.origin"] = seg.Origin
+	}
+
+	// error information
+	if seg.Error {
+		tags["xray.error"] = "true"
+	}
+	if seg.Throttle {
+		tags["xray.throttle"] = "true"
+	}
+	if seg.Fault {
+		tags["xray.fault"] = "true"
+	}
+	if seg.Error || seg.Throttle || seg.Fault {
+		msg := "true"
+		if seg.Cause != nil && len(seg.Cause.Exceptions) > 0 {
+			msg = seg.Cause.Exceptions[0].Message
+		}
+		tags["error"] = msg
+	}
+
+	if http := seg.HTTP; http != nil {
+		if req := http.Request; req != nil {
+			setTag(tags, "http.method", req.Method)
+			setTag(tags, "http.url", req.URL)
+			setTag(tags, "http.user_agent", req.UserAgent)
+			setTag(tags, "http.client_ip", req.ClientIP)
+		}
+		if resp := http.Response; resp != nil {
+			if resp.Status != 0 {
+				tags["http.status_code"] = strconv.Itoa(resp.Status)
+			}
+			if resp.ContentLength != 0 {
+				tags["http.response.content_length"] = strconv.FormatInt(resp.ContentLength, 10)
+			}
+		}
+	}
+
+	if sql := seg.SQL; sql != nil {
+		setTag(tags, "sql.url", sql.URL)
+		setTag(tags, "sql.query", sql.SanitizedQuery)
+		setTag(tags, "sql.database_type", sql.DatabaseType)
+		setTag(tags, "sql.database_version", sql.DatabaseVersion)
+		setTag(tags, "sql.driver_version", sql.DriverVersion)
+		setTag(tags, "sql.user", sql.User)
+		setTag(tags, "sql.preparation", sql.Preparation)
+	}
+
+	for key, value := range seg.AWS {
+		if key == "xray" {
+			// the information of the SDK is not useful for Zipkin.
+			continue
+		}
+		tags["aws."+key] = stringify(value)
+	}
+
+	for key, value := range seg.Annotations {
+		tags["annotation."+key] = fmt.Sprint(value)
+	}
+
+	for namespace, values := range seg.Metadata {
+		m, ok := values.(map[string]interface{})
+		if !ok {
+			tags["metadata."+namespace] = stringify(values)
+			continue
+		}
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			tags["metadata."+namespace+"."+key] = stringify(m[key])
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+func setTag(tags map[string]string, key, value string) {
+	if value != "" {
+		tags[key] = value
+	}
+}
+
+func stringify(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,158 @@
+package zipkin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func TestConvert(t *testing.T) {
+	doc := &schema.Segment{
+		Name:      "my-service",
+		ID:        "03babb4ba280be51",
+		TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		ParentID:  "53995c3f42cd8ad8",
+		Type:      "subsegment",
+		StartTime: 1000000000,
+		EndTime:   1000000001,
+		Service:   &schema.Service{},
+		Fault:     true,
+		HTTP: &schema.HTTP{
+			Request: &schema.HTTPRequest{
+				Method: "GET",
+				URL:    "http://example.com/foo",
+			},
+			Response: &schema.HTTPResponse{
+				Status:        500,
+				ContentLength: 42,
+			},
+		},
+		AWS: schema.AWS{
+			"xray":      map[string]interface{}{"sdk": "X-Ray for Go"},
+			"operation": "GetItem",
+		},
+		Annotations: map[string]interface{}{
+			"route": "/foo",
+			"count": int64(42),
+		},
+		Metadata: map[string]interface{}{
+			"default": map[string]interface{}{
+				"key": map[string]interface{}{"foo": "bar"},
+			},
+		},
+		Cause: &schema.Cause{
+			Exceptions: []schema.Exception{{Message: "some error"}},
+		},
+		Subsegments: []*schema.Segment{
+			{
+				Name:       "in-progress",
+				ID:         "1000000000000001",
+				StartTime:  1000000000.25,
+				InProgress: true,
+				Subsegments: []*schema.Segment{
+					{
+						Name:      "db",
+						ID:        "1000000000000002",
+						Namespace: "remote",
+						StartTime: 1000000000.25,
+						EndTime:   1000000000.5,
+						SQL: &schema.SQL{
+							URL:            "postgres://localhost/db",
+							SanitizedQuery: "SELECT 1",
+						},
+					},
+				},
+			},
+		},
+	}
+	got, err := Convert(doc, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := &Endpoint{ServiceName: "my-service"}
+	want := []*Span{
+		{
+			TraceID:       "5e645f3e1dfad076a177c5ccc5de12f5",
+			ParentID:      "53995c3f42cd8ad8",
+			ID:            "03babb4ba280be51",
+			Kind:          KindServer,
+			Name:          "my-service",
+			Timestamp:     1000000000000000,
+			Duration:      1000000,
+			LocalEndpoint: local,
+			Tags: map[string]string{
+				"error":                        "some error",
+				"xray.fault":                   "true",
+				"http.method":                  "GET",
+				"http.url":                     "http://example.com/foo",
+				"http.status_code":             "500",
+				"http.response.content_length": "42",
+				"aws.operation":                "GetItem",
+				"annotation.route":             "/foo",
+				"annotation.count":             "42",
+				"metadata.default.key":         `{"foo":"bar"}`,
+			},
+		},
+		{
+			TraceID:        "5e645f3e1dfad076a177c5ccc5de12f5",
+			ParentID:       "1000000000000001",
+			ID:             "1000000000000002",
+			Kind:           KindClient,
+			Name:           "db",
+			Timestamp:      1000000000250000,
+			Duration:       250000,
+			LocalEndpoint:  local,
+			RemoteEndpoint: &Endpoint{ServiceName: "db"},
+			Tags: map[string]string{
+				"xray.namespace": "remote",
+				"sql.url":        "postgres://localhost/db",
+				"sql.query":      "SELECT 1",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvert_IndependentSubsegment(t *testing.T) {
+	doc := &schema.Segment{
+		Name:      "sub",
+		ID:        "1000000000000001",
+		TraceID:   "1-5e645f3e-1dfad076a177c5ccc5de12f5",
+		ParentID:  "03babb4ba280be51",
+		Type:      "subsegment",
+		StartTime: 1000000000,
+		EndTime:   1000000000,
+	}
+	got, err := Convert(doc, "my-service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Span{
+		{
+			TraceID:       "5e645f3e1dfad076a177c5ccc5de12f5",
+			ParentID:      "03babb4ba280be51",
+			ID:            "1000000000000001",
+			Name:          "sub",
+			Timestamp:     1000000000000000,
+			Duration:      1,
+			LocalEndpoint: &Endpoint{ServiceName: "my-service"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvert_InvalidTraceID(t *testing.T) {
+	_, err := Convert(&schema.Segment{TraceID: "invalid"}, "")
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if errors.Unwrap(err) == nil {
+		t.Errorf("want wrapped error, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package xrayhttp
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// countingBody counts the bytes read from the body.
+// It may be read by the other goroutines, e.g. the request body of http.Transport.
+type countingBody struct {
+	io.ReadCloser
+	n int64 // accessed atomically
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.n, int64(n))
+	return n, err
+}
+
+func (b *countingBody) count() int64 {
+	return atomic.LoadInt64(&b.n)
+}
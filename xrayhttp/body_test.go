@@ -0,0 +1,238 @@
+package xrayhttp
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestHandler_RequestBytesRead(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(FixedTracingNamer("test"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			panic(err)
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("hello world"))
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, _ := got.Metadata["http"].(map[string]interface{})
+	if md["request_bytes_read"] != float64(len("hello world")) {
+		t.Errorf("want %d, got %v", len("hello world"), md["request_bytes_read"])
+	}
+}
+
+func TestClient_BodyBytes(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.Copy(w, r.Body); err != nil {
+			panic(err)
+		}
+		// send the body in chunked encoding.
+		w.(http.Flusher).Flush()
+	}))
+	defer ts.Close()
+
+	func() {
+		client := Client(nil)
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("hello world"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// send the body in chunked encoding.
+		req.ContentLength = -1
+		req = req.WithContext(ctx)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := got.Subsegments[0]
+	md, _ := remote.Metadata["http"].(map[string]interface{})
+	if md["request_bytes_written"] != float64(len("hello world")) {
+		t.Errorf("want %d, got %v", len("hello world"), md["request_bytes_written"])
+	}
+	if md["response_bytes_read"] != float64(len("hello world")) {
+		t.Errorf("want %d, got %v", len("hello world"), md["response_bytes_read"])
+	}
+	if _, ok := md["early_close"]; ok {
+		t.Error("want no early_close")
+	}
+	if remote.HTTP.Response.ContentLength != int64(len("hello world")) {
+		t.Errorf("want %d, got %d", len("hello world"), remote.HTTP.Response.ContentLength)
+	}
+}
+
+func TestClient_BodyBytesWithoutHTTPTrace(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	// the round tripper that doesn't call the hooks of httptrace.
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if _, err := ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{},
+			Body:          ioutil.NopCloser(strings.NewReader("hello world")),
+			ContentLength: -1,
+			Request:       req,
+		}, nil
+	})
+
+	func() {
+		client := Client(&http.Client{Transport: base})
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(ctx)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := got.Subsegments[0]
+	md, _ := remote.Metadata["http"].(map[string]interface{})
+	if md["request_bytes_written"] != float64(len("hello")) {
+		t.Errorf("want %d, got %v", len("hello"), md["request_bytes_written"])
+	}
+	if md["response_bytes_read"] != float64(len("hello world")) {
+		t.Errorf("want %d, got %v", len("hello world"), md["response_bytes_read"])
+	}
+	if remote.HTTP.Response.ContentLength != int64(len("hello world")) {
+		t.Errorf("want %d, got %d", len("hello world"), remote.HTTP.Response.ContentLength)
+	}
+}
+
+func TestClient_EarlyClose(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("a", 1024))
+	}))
+	defer ts.Close()
+
+	func() {
+		client := Client(nil)
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(ctx)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf [10]byte
+		if _, err := io.ReadFull(resp.Body, buf[:]); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, _ := got.Subsegments[0].Metadata["http"].(map[string]interface{})
+	if md["response_bytes_read"] != float64(10) {
+		t.Errorf("want %d, got %v", 10, md["response_bytes_read"])
+	}
+	if md["early_close"] != true {
+		t.Errorf("want early_close, got %v", md["early_close"])
+	}
+}
+
+func TestClient_ReadContentLength(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "13")
+		io.WriteString(w, `{"foo":"bar"}`)
+	}))
+	defer ts.Close()
+
+	func() {
+		client := Client(nil)
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(ctx)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// read exactly Content-Length bytes, without reading EOF.
+		buf := make([]byte, resp.ContentLength)
+		if _, err := io.ReadFull(resp.Body, buf); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, _ := got.Subsegments[0].Metadata["http"].(map[string]interface{})
+	if md["response_bytes_read"] != float64(13) {
+		t.Errorf("want %d, got %v", 13, md["response_bytes_read"])
+	}
+	if _, ok := md["early_close"]; ok {
+		t.Error("want no early_close")
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -17,77 +17,129 @@ const emptyHostRename = "empty_host_error"
 // Client creates a shallow copy of the provided http client,
 // defaulting to http.DefaultClient, with roundtripper wrapped
 // with xrayhttp.RoundTripper.
-func Client(client *http.Client) *http.Client {
+func Client(client *http.Client, opts ...ClientOption) *http.Client {
 	if client == nil {
 		client = http.DefaultClient
 	}
 	ret := *client
-	ret.Transport = RoundTripper(ret.Transport)
+	ret.Transport = RoundTripper(ret.Transport, opts...)
 	return &ret
 }
 
 // RoundTripper wraps the provided http roundtripper with xray.Capture,
 // sets HTTP-specific xray fields, and adds the trace header to the outbound request.
-func RoundTripper(rt http.RoundTripper) http.RoundTripper {
+func RoundTripper(rt http.RoundTripper, opts ...ClientOption) http.RoundTripper {
 	if rt == nil {
 		rt = http.DefaultTransport
 	}
-	if _, ok := rt.(*roundtripper); ok {
+	if xrt, ok := rt.(*roundtripper); ok {
 		// X-Ray SDK is already installed
-		return rt
+		if len(opts) == 0 {
+			return rt
+		}
+		rt = xrt.Base
 	}
 	return &roundtripper{
 		Base: rt,
+		cfg:  newClientConfig(opts),
 	}
 }
 
 type roundtripper struct {
 	Base http.RoundTripper
+	cfg  clientConfig
 }
 
-func (rt *roundtripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	var isEmptyHost bool
+// remoteName returns the name of the remote subsegment, and whether the host is empty.
+func (rt *roundtripper) remoteName(req *http.Request) (string, bool) {
 	host := req.Host
 	if host == "" {
-		if h := req.URL.Host; h != "" {
-			host = h
-		} else {
-			host = emptyHostRename
-			isEmptyHost = true
-		}
+		host = req.URL.Host
+	}
+	if host == "" {
+		return emptyHostRename, true
+	}
+	return rt.cfg.name(req, host), false
+}
+
+func (rt *roundtripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !rt.cfg.shouldTrace(req) {
+		return rt.Base.RoundTrip(req)
 	}
 
 	ctx := req.Context()
-	req.Header.Set(xray.TraceIDHeaderKey, xray.DownstreamHeader(ctx).String())
+	if rt.cfg.shouldPropagate(req) {
+		req.Header.Set(xray.TraceIDHeaderKey, xray.DownstreamHeader(ctx).String())
+	}
 
-	ctx, seg := xray.BeginSubsegment(ctx, host)
-	defer seg.Close()
-	if !isEmptyHost {
+	attempt, isAttempt := ctx.Value(attemptContextKey).(int)
+	name, isEmptyHost := rt.remoteName(req)
+	if isAttempt {
+		// the remote node is recorded by the subsegment of the logical call.
+		name = "attempt"
+		ctx = context.WithValue(ctx, attemptContextKey, nil)
+	}
+	ctx, seg := xray.BeginSubsegment(ctx, name)
+	if isAttempt {
+		seg.AddMetadataToNamespace("http", "attempt", attempt)
+	} else if !isEmptyHost {
 		seg.SetNamespace("remote")
 	}
 
 	requestInfo := &schema.HTTPRequest{
 		Method: req.Method,
 		URL:    req.URL.String(),
+		Traced: rt.cfg.downstreamTraced(req),
 	}
 	seg.SetHTTPRequest(requestInfo)
 
 	// set trace hooks
 	ctx, cancel := WithClientTrace(ctx)
 	defer cancel()
-	respTracer := &clientResponseTracer{BaseContext: ctx}
+
+	// the remote subsegment lasts until the response body is closed.
+	respTracer := &clientResponseTracer{BaseContext: ctx, remote: seg}
+	defer func() {
+		if err := recover(); err != nil {
+			seg.AddPanic(err)
+			respTracer.Close()
+			panic(err)
+		}
+	}()
 	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
 		GotFirstResponseByte: respTracer.GotFirstResponseByte,
 	})
 	req = req.WithContext(ctx)
+	var body *countingBody
+	if req.Body != nil && req.Body != http.NoBody {
+		body = &countingBody{ReadCloser: req.Body}
+		req.Body = body
+	}
 
 	resp, err := rt.Base.RoundTrip(req)
+	if body != nil {
+		respTracer.requestBytes = body.count()
+	}
 	if err != nil {
 		seg.AddError(err)
 		respTracer.Close()
 		return nil, err
 	}
 
+	respTracer.response = setHTTPResponse(seg, resp)
+	respTracer.contentLength = resp.ContentLength
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		respTracer.Close()
+	} else {
+		respTracer.body = resp.Body
+		respTracer.eof = resp.Body == http.NoBody
+		resp.Body = respTracer
+	}
+	return resp, nil
+}
+
+// setHTTPResponse records the response, and marks the segment by its status code.
+func setHTTPResponse(seg *xray.Segment, resp *http.Response) *schema.HTTPResponse {
 	responseInfo := &schema.HTTPResponse{
 		Status: resp.StatusCode,
 	}
@@ -104,21 +156,31 @@ func (rt *roundtripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	if resp.StatusCode >= 500 && resp.StatusCode < 600 {
 		seg.SetFault()
 	}
-	if resp.StatusCode == http.StatusSwitchingProtocols {
-		respTracer.Close()
-	} else {
-		respTracer.body = resp.Body
-		resp.Body = respTracer
-	}
-	return resp, err
+	return responseInfo
 }
 
+// clientResponseTracer traces reading the response body.
+// It records the bytes of the request and the response in the remote subsegment, and closes it when the body is closed.
 type clientResponseTracer struct {
 	BaseContext context.Context
 	mu          sync.RWMutex
 	body        io.ReadCloser
 	ctx         context.Context
 	seg         *xray.Segment
+
+	// the remote subsegment and its response.
+	remote   *xray.Segment
+	response *schema.HTTPResponse
+
+	// the bytes read from the body, and whether the body is read until EOF.
+	n   int64
+	eof bool
+
+	// the length of the response body, -1 means unknown.
+	contentLength int64
+
+	// the bytes written in the request body.
+	requestBytes int64
 }
 
 func (r *clientResponseTracer) GotFirstResponseByte() {
@@ -134,10 +196,23 @@ func (r *clientResponseTracer) Read(b []byte) (int, error) {
 	r.mu.RLock()
 	body := r.body
 	r.mu.RUnlock()
-	if body != nil {
-		return body.Read(b)
+	if body == nil {
+		return 0, io.EOF
+	}
+	n, err := body.Read(b)
+	r.mu.Lock()
+	r.n += int64(n)
+	if err == io.EOF && !r.eof {
+		r.eof = true
+		if r.contentLength < 0 && r.response != nil {
+			// the length is unknown until EOF, e.g. chunked encoding.
+			response := *r.response
+			response.ContentLength = r.n
+			r.remote.SetHTTPResponse(&response)
+		}
 	}
-	return 0, io.EOF
+	r.mu.Unlock()
+	return n, err
 }
 
 func (r *clientResponseTracer) Close() error {
@@ -152,5 +227,18 @@ func (r *clientResponseTracer) Close() error {
 		r.seg.Close()
 		r.ctx, r.seg = nil, nil
 	}
+	if r.requestBytes > 0 {
+		r.remote.AddMetadataToNamespace("http", "request_bytes_written", r.requestBytes)
+	}
+	if r.n > 0 {
+		r.remote.AddMetadataToNamespace("http", "response_bytes_read", r.n)
+	}
+	if r.body != nil && !r.eof && r.contentLength >= 0 && r.n < r.contentLength {
+		// the body is closed before reading all of it.
+		// if the length is unknown, it can't be distinguished from the readers that stop at the end of the value,
+		// e.g. json.Decoder, so it is not reported.
+		r.remote.AddMetadataToNamespace("http", "early_close", true)
+	}
+	r.remote.Close()
 	return err
 }
@@ -0,0 +1,129 @@
+package xrayhttp
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithTrustedProxies configures the proxies that are trusted to set the forwarding headers.
+// Each entry is a CIDR, e.g. "10.0.0.0/8", or an IP address.
+// It panics if an entry is invalid.
+//
+// With trusted proxies, the client IP is resolved by the Forwarded header (RFC 7239),
+// the X-Forwarded-For header or the X-Real-IP header, only if the request comes from a trusted proxy.
+// The forwarding chain is walked from the right, and the first untrusted address is used as the client IP.
+// Without trusted proxies, the first entry of the X-Forwarded-For header is used for backward compatibility.
+func WithTrustedProxies(cidrs ...string) HandlerOption {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		nets = append(nets, parseCIDR(cidr))
+	}
+	return func(cfg *handlerConfig) {
+		cfg.trustedProxies = append(cfg.trustedProxies, nets...)
+	}
+}
+
+func parseCIDR(cidr string) *net.IPNet {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			panic(fmt.Sprintf("xrayhttp: invalid trusted proxy: %q", cidr))
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			bits = 8 * net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(fmt.Sprintf("xrayhttp: invalid trusted proxy: %q", cidr))
+	}
+	return ipnet
+}
+
+func (cfg *handlerConfig) clientIP(r *http.Request) (string, bool) {
+	if len(cfg.trustedProxies) == 0 {
+		return clientIP(r)
+	}
+
+	remote := parseIP(r.RemoteAddr)
+	if remote == nil {
+		return r.RemoteAddr, false
+	}
+	if !cfg.trusted(remote) {
+		return remote.String(), false
+	}
+
+	var chain []string
+	if values := r.Header["Forwarded"]; len(values) > 0 {
+		chain = parseForwarded(values)
+	} else if values := r.Header["X-Forwarded-For"]; len(values) > 0 {
+		for _, v := range values {
+			chain = append(chain, strings.Split(v, ",")...)
+		}
+	} else if v := r.Header.Get("X-Real-IP"); v != "" {
+		chain = []string{v}
+	}
+
+	// walk the chain from the right, and find the first untrusted address.
+	client, forwarded := remote, false
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseIP(strings.TrimSpace(chain[i]))
+		if ip == nil {
+			// unknown or obfuscated identifiers, e.g. "unknown" and "_hidden".
+			// the client is not identified beyond here.
+			break
+		}
+		client, forwarded = ip, true
+		if !cfg.trusted(ip) {
+			break
+		}
+	}
+	return client.String(), forwarded
+}
+
+func (cfg *handlerConfig) trusted(ip net.IP) bool {
+	for _, ipnet := range cfg.trustedProxies {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseForwarded returns the values of the "for" parameters of the Forwarded headers.
+func parseForwarded(values []string) []string {
+	var ret []string
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				pair = strings.TrimSpace(pair)
+				idx := strings.IndexByte(pair, '=')
+				if idx < 0 || !strings.EqualFold(pair[:idx], "for") {
+					continue
+				}
+				ret = append(ret, strings.Trim(pair[idx+1:], `"`))
+			}
+		}
+	}
+	return ret
+}
+
+// parseIP parses the IP address with an optional port,
+// e.g. "192.0.2.1", "192.0.2.1:8080", "2001:db8::1", "[2001:db8::1]" and "[2001:db8::1]:8080".
+func parseIP(s string) net.IP {
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return net.ParseIP(s[1 : len(s)-1])
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return nil
+}
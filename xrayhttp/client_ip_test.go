@@ -0,0 +1,121 @@
+package xrayhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	cfg := newHandlerConfig([]HandlerOption{
+		WithTrustedProxies("10.0.0.0/8", "2001:db8::/32", "192.0.2.100"),
+	})
+
+	tc := []struct {
+		name          string
+		remoteAddr    string
+		header        map[string]string
+		wantIP        string
+		wantForwarded bool
+	}{
+		{
+			name:       "no proxy",
+			remoteAddr: "198.51.100.1:1234",
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "untrusted remote",
+			remoteAddr: "198.51.100.1:1234",
+			header:     map[string]string{"X-Forwarded-For": "203.0.113.1"},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:          "walk x-forwarded-for from the right",
+			remoteAddr:    "10.0.0.1:1234",
+			header:        map[string]string{"X-Forwarded-For": "203.0.113.99, 203.0.113.1, 10.0.0.2"},
+			wantIP:        "203.0.113.1",
+			wantForwarded: true,
+		},
+		{
+			name:          "all trusted",
+			remoteAddr:    "10.0.0.1:1234",
+			header:        map[string]string{"X-Forwarded-For": "192.0.2.100, 10.0.0.2"},
+			wantIP:        "192.0.2.100",
+			wantForwarded: true,
+		},
+		{
+			name:       "trusted remote without headers",
+			remoteAddr: "10.0.0.1:1234",
+			wantIP:     "10.0.0.1",
+		},
+		{
+			name:          "forwarded",
+			remoteAddr:    "10.0.0.1:1234",
+			header:        map[string]string{"Forwarded": `for=203.0.113.99, for="[2001:db9::17]:4711";proto=https, for=10.0.0.2`},
+			wantIP:        "2001:db9::17",
+			wantForwarded: true,
+		},
+		{
+			name:       "forwarded takes precedence",
+			remoteAddr: "10.0.0.1:1234",
+			header: map[string]string{
+				"Forwarded":       "for=203.0.113.2",
+				"X-Forwarded-For": "203.0.113.1",
+			},
+			wantIP:        "203.0.113.2",
+			wantForwarded: true,
+		},
+		{
+			name:       "obfuscated identifier",
+			remoteAddr: "10.0.0.1:1234",
+			header:     map[string]string{"Forwarded": "for=_hidden, for=10.0.0.2"},
+			// the client is not identified beyond the last trusted proxy.
+			wantIP:        "10.0.0.2",
+			wantForwarded: true,
+		},
+		{
+			name:          "x-real-ip",
+			remoteAddr:    "[2001:db8::1]:1234",
+			header:        map[string]string{"X-Real-IP": "203.0.113.1"},
+			wantIP:        "203.0.113.1",
+			wantForwarded: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			ip, forwarded := cfg.clientIP(req)
+			if ip != tt.wantIP {
+				t.Errorf("want %q, got %q", tt.wantIP, ip)
+			}
+			if forwarded != tt.wantForwarded {
+				t.Errorf("want %t, got %t", tt.wantForwarded, forwarded)
+			}
+		})
+	}
+}
+
+func TestClientIP_Legacy(t *testing.T) {
+	cfg := newHandlerConfig(nil)
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.99, 203.0.113.1")
+	ip, forwarded := cfg.clientIP(req)
+	if ip != "203.0.113.99" || !forwarded {
+		t.Errorf("want %q and true, got %q and %t", "203.0.113.99", ip, forwarded)
+	}
+}
+
+func TestWithTrustedProxies_Invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic, but not")
+		}
+	}()
+	WithTrustedProxies("10.0.0.0/33")
+}
@@ -0,0 +1,75 @@
+package xrayhttp
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+// WithTracingNamer names the subsegments of the outgoing requests by tn.
+// If tn returns empty string, the host of the request is used.
+func WithTracingNamer(tn TracingNamer) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.namer = tn
+	}
+}
+
+// WithDownstreamTraced marks the outgoing requests as traced if f returns true.
+// Use it when the downstream service is known to be instrumented.
+func WithDownstreamTraced(f RequestFilter) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.traced = f
+	}
+}
+
+// TracingNamerFunc is an adapter to allow the use of ordinary functions as TracingNamer.
+type TracingNamerFunc func(r *http.Request) string
+
+// TracingName implements TracingNamer.
+func (f TracingNamerFunc) TracingName(r *http.Request) string {
+	return f(r)
+}
+
+// HostTracingNamer maps the hosts to the logical service names, e.g. "10.0.3.17:8080" to "user-service".
+// The host with the port is looked up first, and then the host without the port.
+type HostTracingNamer map[string]string
+
+// TracingName implements TracingNamer.
+func (tn HostTracingNamer) TracingName(r *http.Request) string {
+	host := requestHost(r)
+	if name, ok := tn[host]; ok {
+		return name
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return tn[h]
+	}
+	return ""
+}
+
+// HostPattern is a pair of a host pattern and a service name for PatternTracingNamer.
+type HostPattern struct {
+	// Pattern is the pattern of the host without the port.
+	// It may contain the wildcards '*' and '?', e.g. "*.svc.cluster.local".
+	Pattern string
+
+	// Name is the service name.
+	Name string
+}
+
+// PatternTracingNamer names the requests by the first matched host pattern.
+type PatternTracingNamer []HostPattern
+
+// TracingName implements TracingNamer.
+func (tn PatternTracingNamer) TracingName(r *http.Request) string {
+	host := requestHost(r)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, p := range tn {
+		if sampling.WildcardMatch(p.Pattern, host, true) {
+			return p.Name
+		}
+	}
+	return ""
+}
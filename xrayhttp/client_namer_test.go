@@ -0,0 +1,114 @@
+package xrayhttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+var _ TracingNamer = HostTracingNamer{}
+var _ TracingNamer = PatternTracingNamer{}
+var _ TracingNamer = TracingNamerFunc(nil)
+
+func TestHostTracingNamer(t *testing.T) {
+	namer := HostTracingNamer{
+		"10.0.3.17:8080": "user-service",
+		"10.0.3.18":      "item-service",
+	}
+	tc := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://10.0.3.17:8080/", want: "user-service"},
+		{url: "http://10.0.3.17:9090/", want: ""},
+		{url: "http://10.0.3.18:8080/", want: "item-service"},
+		{url: "http://10.0.3.18/", want: "item-service"},
+	}
+	for _, tt := range tc {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := namer.TracingName(req); got != tt.want {
+			t.Errorf("%s: want %q, got %q", tt.url, tt.want, got)
+		}
+	}
+}
+
+func TestPatternTracingNamer(t *testing.T) {
+	namer := PatternTracingNamer{
+		{Pattern: "user.*.svc.cluster.local", Name: "user-service"},
+		{Pattern: "*.svc.cluster.local", Name: "cluster"},
+	}
+	tc := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://user.default.svc.cluster.local:8080/", want: "user-service"},
+		{url: "http://item.default.svc.cluster.local/", want: "cluster"},
+		{url: "http://example.com/", want: ""},
+	}
+	for _, tt := range tc {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := namer.TracingName(req); got != tt.want {
+			t.Errorf("%s: want %q, got %q", tt.url, tt.want, got)
+		}
+	}
+}
+
+func TestClient_WithTracingNamer(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	func() {
+		client := Client(
+			nil,
+			WithTracingNamer(TracingNamerFunc(func(r *http.Request) string {
+				return "my-service"
+			})),
+			WithDownstreamTraced(AllowHosts("127.0.0.1")),
+		)
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(ctx)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Subsegments) != 1 {
+		t.Fatalf("want 1 subsegment, got %d", len(got.Subsegments))
+	}
+	sub := got.Subsegments[0]
+	if sub.Name != "my-service" {
+		t.Errorf("want %q, got %q", "my-service", sub.Name)
+	}
+	if sub.Namespace != "remote" {
+		t.Errorf("want %q, got %q", "remote", sub.Namespace)
+	}
+	if !sub.HTTP.Request.Traced {
+		t.Error("want traced, got not")
+	}
+}
@@ -0,0 +1,108 @@
+package xrayhttp
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+// ClientOption is an option for Client and RoundTripper.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	propagate RequestFilter
+	trace     RequestFilter
+	namer     TracingNamer
+	traced    RequestFilter
+}
+
+// RequestFilter reports whether the outgoing request matches a condition.
+type RequestFilter func(req *http.Request) bool
+
+// AllowHosts returns a RequestFilter that matches the requests to the hosts.
+// The patterns may contain the wildcards '*' and '?', e.g. "*.example.com".
+// The port of the host is ignored.
+func AllowHosts(patterns ...string) RequestFilter {
+	return func(req *http.Request) bool {
+		return matchHost(patterns, req)
+	}
+}
+
+// DenyHosts returns a RequestFilter that matches the requests except to the hosts.
+// The patterns may contain the wildcards '*' and '?', e.g. "*.amazonaws.com".
+// The port of the host is ignored.
+func DenyHosts(patterns ...string) RequestFilter {
+	return func(req *http.Request) bool {
+		return !matchHost(patterns, req)
+	}
+}
+
+func matchHost(patterns []string, req *http.Request) bool {
+	host := requestHost(req)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, pattern := range patterns {
+		if sampling.WildcardMatchCaseInsensitive(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// WithPropagation adds the trace header to the outgoing requests only if f returns true.
+// The other requests are still traced, but the downstream doesn't know the trace.
+// It is useful for the third-party APIs and the pre-signed URLs, where the extra header breaks signatures.
+// By default, the trace header is added to all requests.
+func WithPropagation(f RequestFilter) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.propagate = f
+	}
+}
+
+// WithTracing traces the outgoing requests only if f returns true.
+// The other requests are sent without subsegments and the trace header.
+// By default, all requests are traced.
+func WithTracing(f RequestFilter) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.trace = f
+	}
+}
+
+func newClientConfig(opts []ClientOption) clientConfig {
+	var cfg clientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+func (cfg *clientConfig) shouldPropagate(req *http.Request) bool {
+	return cfg.propagate == nil || cfg.propagate(req)
+}
+
+func (cfg *clientConfig) shouldTrace(req *http.Request) bool {
+	return cfg.trace == nil || cfg.trace(req)
+}
+
+func (cfg *clientConfig) name(req *http.Request, host string) string {
+	if cfg.namer == nil {
+		return host
+	}
+	if name := cfg.namer.TracingName(req); name != "" {
+		return name
+	}
+	return host
+}
+
+func (cfg *clientConfig) downstreamTraced(req *http.Request) bool {
+	return cfg.traced != nil && cfg.traced(req)
+}
@@ -0,0 +1,133 @@
+package xrayhttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestRequestFilter(t *testing.T) {
+	tc := []struct {
+		filter RequestFilter
+		url    string
+		want   bool
+	}{
+		{filter: AllowHosts("example.com"), url: "http://example.com/", want: true},
+		{filter: AllowHosts("example.com"), url: "http://example.com:8080/", want: true},
+		{filter: AllowHosts("*.example.com"), url: "http://api.EXAMPLE.com/", want: true},
+		{filter: AllowHosts("*.example.com"), url: "http://example.org/", want: false},
+		{filter: DenyHosts("*.amazonaws.com"), url: "https://bucket.s3.amazonaws.com/key", want: false},
+		{filter: DenyHosts("*.amazonaws.com"), url: "http://example.com/", want: true},
+	}
+	for _, tt := range tc {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tt.filter(req); got != tt.want {
+			t.Errorf("%s: want %t, got %t", tt.url, tt.want, got)
+		}
+	}
+}
+
+func TestClient_WithPropagation(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	ch := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ch <- r.Header.Get(xray.TraceIDHeaderKey)
+	}))
+	defer ts.Close()
+
+	func() {
+		client := Client(nil, WithPropagation(DenyHosts("127.0.0.1")))
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(ctx)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if header := <-ch; header != "" {
+		t.Errorf("want no trace header, got %q", header)
+	}
+
+	// the request is still traced.
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Subsegments) != 1 || got.Subsegments[0].HTTP == nil {
+		t.Errorf("want a subsegment for the request, got %v", got.Subsegments)
+	}
+}
+
+func TestClient_WithTracing(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	ch := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ch <- r.Header.Get(xray.TraceIDHeaderKey)
+	}))
+	defer ts.Close()
+
+	func() {
+		client := Client(nil, WithTracing(AllowHosts("example.com")))
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(ctx)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if header := <-ch; header != "" {
+		t.Errorf("want no trace header, got %q", header)
+	}
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Subsegments) != 0 {
+		t.Errorf("want no subsegments, got %v", got.Subsegments)
+	}
+}
+
+func TestRoundTripper_Rewrap(t *testing.T) {
+	rt := RoundTripper(nil)
+	if RoundTripper(rt) != rt {
+		t.Error("want the same round tripper")
+	}
+	wrapped, ok := RoundTripper(rt, WithTracing(AllowHosts("example.com"))).(*roundtripper)
+	if !ok {
+		t.Fatal("want *roundtripper")
+	}
+	if wrapped.Base != http.DefaultTransport {
+		t.Error("want the round tripper is not wrapped twice")
+	}
+}
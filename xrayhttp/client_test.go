@@ -37,6 +37,28 @@ func ignoreVariableFieldFunc(in *schema.Segment) *schema.Segment {
 			out.Cause.Exceptions[i].ID = ""
 		}
 	}
+	if md, ok := out.Metadata["http"].(map[string]interface{}); ok {
+		// the timing of the responses.
+		httpMetadata := make(map[string]interface{}, len(md))
+		for k, v := range md {
+			if k != "time_to_first_byte" && k != "time_to_last_write" {
+				httpMetadata[k] = v
+			}
+		}
+		metadata := make(map[string]interface{}, len(out.Metadata))
+		for k, v := range out.Metadata {
+			metadata[k] = v
+		}
+		if len(httpMetadata) > 0 {
+			metadata["http"] = httpMetadata
+		} else {
+			delete(metadata, "http")
+		}
+		out.Metadata = metadata
+		if len(out.Metadata) == 0 {
+			out.Metadata = nil
+		}
+	}
 	for _, sub := range in.Subsegments {
 		out.Subsegments = append(out.Subsegments, ignoreVariableFieldFunc(sub))
 	}
@@ -99,6 +121,11 @@ func TestClient(t *testing.T) {
 			{
 				Name:      "example.com",
 				Namespace: "remote",
+				Metadata: map[string]interface{}{
+					"http": map[string]interface{}{
+						"response_bytes_read": 5.0,
+					},
+				},
 				HTTP: &schema.HTTP{
 					Request: &schema.HTTPRequest{
 						Method: http.MethodGet,
@@ -199,6 +226,11 @@ func TestClient_StatusTooManyRequests(t *testing.T) {
 			{
 				Name:      "example.com",
 				Namespace: "remote",
+				Metadata: map[string]interface{}{
+					"http": map[string]interface{}{
+						"response_bytes_read": 5.0,
+					},
+				},
 				HTTP: &schema.HTTP{
 					Request: &schema.HTTPRequest{
 						Method: http.MethodGet,
@@ -301,6 +333,11 @@ func TestClient_StatusInternalServerError(t *testing.T) {
 			{
 				Name:      "example.com",
 				Namespace: "remote",
+				Metadata: map[string]interface{}{
+					"http": map[string]interface{}{
+						"response_bytes_read": 5.0,
+					},
+				},
 				HTTP: &schema.HTTP{
 					Request: &schema.HTTPRequest{
 						Method: http.MethodGet,
@@ -408,6 +445,11 @@ func TestClient_TLS(t *testing.T) {
 			{
 				Name:      "example.com",
 				Namespace: "remote",
+				Metadata: map[string]interface{}{
+					"http": map[string]interface{}{
+						"response_bytes_read": 5.0,
+					},
+				},
 				HTTP: &schema.HTTP{
 					Request: &schema.HTTPRequest{
 						Method: http.MethodGet,
@@ -529,6 +571,11 @@ func TestClient_DNS(t *testing.T) {
 			{
 				Name:      "example.com",
 				Namespace: "remote",
+				Metadata: map[string]interface{}{
+					"http": map[string]interface{}{
+						"response_bytes_read": 5.0,
+					},
+				},
 				HTTP: &schema.HTTP{
 					Request: &schema.HTTPRequest{
 						Method: http.MethodGet,
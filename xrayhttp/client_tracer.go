@@ -3,10 +3,10 @@ package xrayhttp
 import (
 	"context"
 	"crypto/tls"
-	"fmt"
 	"net/http/httptrace"
 	"sync"
 
+	"github.com/shogo82148/aws-xray-yasdk-go/internal/nettrace"
 	"github.com/shogo82148/aws-xray-yasdk-go/xray"
 )
 
@@ -15,12 +15,7 @@ type httpSubsegments struct {
 	ctx     context.Context
 	connCtx context.Context
 	connSeg *xray.Segment
-	dnsCtx  context.Context
-	dnsSeg  *xray.Segment
-	dialCtx context.Context
-	dialSeg *xray.Segment
-	tlsCtx  context.Context
-	tlsSeg  *xray.Segment
+	conn    *nettrace.Subsegments
 	reqCtx  context.Context
 	reqSeg  *xray.Segment
 }
@@ -29,6 +24,7 @@ func (segs *httpSubsegments) GetConn(hostPort string) {
 	segs.mu.Lock()
 	defer segs.mu.Unlock()
 	segs.connCtx, segs.connSeg = xray.BeginSubsegment(segs.ctx, "connect")
+	segs.conn = nettrace.New(segs.connCtx, "http")
 }
 
 func (segs *httpSubsegments) GotConn(info httptrace.GotConnInfo) {
@@ -36,203 +32,80 @@ func (segs *httpSubsegments) GotConn(info httptrace.GotConnInfo) {
 	defer segs.mu.Unlock()
 	if segs.connCtx != nil {
 		segs.connSeg.Close()
-		segs.connCtx, segs.connSeg = nil, nil
+		segs.connCtx, segs.connSeg, segs.conn = nil, nil, nil
 	}
 
 	segs.reqCtx, segs.reqSeg = xray.BeginSubsegment(segs.ctx, "request")
 }
 
-func (segs *httpSubsegments) DNSStart(info httptrace.DNSStartInfo) {
+// connTrace returns the tracer of the connection, nil if no connection is being established.
+func (segs *httpSubsegments) connTrace() *nettrace.Subsegments {
 	segs.mu.Lock()
 	defer segs.mu.Unlock()
-	if segs.connCtx == nil {
-		return
-	}
-	segs.dnsCtx, segs.dnsSeg = xray.BeginSubsegment(segs.connCtx, "dns")
+	return segs.conn
 }
 
-func (segs *httpSubsegments) DNSDone(info httptrace.DNSDoneInfo) {
-	type dnsDoneInfo struct {
-		Addresses []string `json:"addresses"`
-		Coalesced bool     `json:"coalesced"`
-	}
-
+// connFailed closes the "connect" subsegment as a fault.
+func (segs *httpSubsegments) connFailed(conn *nettrace.Subsegments) {
 	segs.mu.Lock()
 	defer segs.mu.Unlock()
-	if segs.dnsCtx == nil {
+	if segs.conn != conn || segs.connCtx == nil {
 		return
 	}
-	addresses := make([]string, 0, len(info.Addrs))
-	for _, addr := range info.Addrs {
-		addresses = append(addresses, addr.String())
-	}
-	meta := dnsDoneInfo{
-		Addresses: addresses,
-		Coalesced: info.Coalesced,
-	}
-	segs.dnsSeg.AddMetadataToNamespace("http", "dns", meta)
-	segs.dnsSeg.AddError(info.Err)
-	segs.dnsSeg.Close()
-	segs.dnsCtx, segs.dnsSeg = nil, nil
-
-	if info.Err != nil && segs.connCtx != nil {
-		segs.connSeg.SetFault()
-		segs.connSeg.Close()
-		segs.connCtx, segs.connSeg = nil, nil
-	}
+	segs.connSeg.SetFault()
+	segs.connSeg.Close()
+	segs.connCtx, segs.connSeg, segs.conn = nil, nil, nil
 }
 
-func (segs *httpSubsegments) ConnectStart(network, addr string) {
-	segs.mu.Lock()
-	defer segs.mu.Unlock()
-	if segs.connCtx == nil {
-		return
+func (segs *httpSubsegments) DNSStart(info httptrace.DNSStartInfo) {
+	if conn := segs.connTrace(); conn != nil {
+		conn.DNSStart(info)
 	}
-	segs.dialCtx, segs.dialSeg = xray.BeginSubsegment(segs.connCtx, "dial")
 }
-func (segs *httpSubsegments) ConnectDone(network, addr string, err error) {
-	type dialInfo struct {
-		Network string `json:"network"`
-		Address string `json:"address"`
-	}
 
-	segs.mu.Lock()
-	defer segs.mu.Unlock()
-	if segs.dialCtx == nil {
+func (segs *httpSubsegments) DNSDone(info httptrace.DNSDoneInfo) {
+	conn := segs.connTrace()
+	if conn == nil {
 		return
 	}
-	segs.dialSeg.AddMetadataToNamespace("http", "dial", dialInfo{
-		Network: network,
-		Address: addr,
-	})
-	segs.dialSeg.AddError(err)
-	segs.dialSeg.Close()
-	segs.dialCtx, segs.dialSeg = nil, nil
-
-	if err != nil && segs.connCtx != nil {
-		segs.connSeg.SetFault()
-		segs.connSeg.Close()
-		segs.connCtx, segs.connSeg = nil, nil
+	conn.DNSDone(info)
+	if info.Err != nil {
+		segs.connFailed(conn)
 	}
 }
 
-func (segs *httpSubsegments) TLSHandshakeStart() {
-	segs.mu.Lock()
-	defer segs.mu.Unlock()
-	if segs.connCtx == nil {
-		return
+func (segs *httpSubsegments) ConnectStart(network, addr string) {
+	if conn := segs.connTrace(); conn != nil {
+		conn.ConnectStart(network, addr)
 	}
-	segs.tlsCtx, segs.tlsSeg = xray.BeginSubsegment(segs.connCtx, "tls")
 }
 
-func (segs *httpSubsegments) TLSHandshakeDone(state tls.ConnectionState, err error) {
-	type tlsInfo struct {
-		Version                    string `json:"version,omitempty"`
-		DidResume                  bool   `json:"did_resume,omitempty"`
-		NegotiatedProtocol         string `json:"negotiated_protocol,omitempty"`
-		NegotiatedProtocolIsMutual bool   `json:"negotiated_protocol_is_mutual,omitempty"`
-		CipherSuite                string `json:"cipher_suite,omitempty"`
-	}
-
-	segs.mu.Lock()
-	defer segs.mu.Unlock()
-	if segs.tlsCtx == nil {
+func (segs *httpSubsegments) ConnectDone(network, addr string, err error) {
+	conn := segs.connTrace()
+	if conn == nil {
 		return
 	}
-	if !segs.tlsSeg.AddError(err) {
-		segs.tlsSeg.AddMetadataToNamespace("http", "tls", tlsInfo{
-			Version:                    tlsVersionName(state.Version),
-			DidResume:                  state.DidResume,
-			NegotiatedProtocol:         state.NegotiatedProtocol,
-			NegotiatedProtocolIsMutual: state.NegotiatedProtocolIsMutual,
-			CipherSuite:                cipherSuiteName(state.CipherSuite),
-		})
-	}
-	segs.tlsSeg.Close()
-	segs.tlsCtx, segs.tlsSeg = nil, nil
-
-	if err != nil && segs.connCtx != nil {
-		segs.connSeg.SetFault()
-		segs.connSeg.Close()
-		segs.connCtx, segs.connSeg = nil, nil
+	conn.ConnectDone(network, addr, err)
+	if err != nil {
+		segs.connFailed(conn)
 	}
 }
 
-func tlsVersionName(version uint16) string {
-	switch version {
-	case tls.VersionSSL30:
-		return "ssl3.0"
-	case tls.VersionTLS10:
-		return "tls1.0"
-	case tls.VersionTLS11:
-		return "tls1.1"
-	case tls.VersionTLS12:
-		return "tls1.2"
-	case 0x0304: // VersionTLS13 is supported from Go 1.13
-		return "tls1.3"
+func (segs *httpSubsegments) TLSHandshakeStart() {
+	if conn := segs.connTrace(); conn != nil {
+		conn.TLSHandshakeStart()
 	}
-
-	// fallback to hex format
-	return fmt.Sprintf("0x%04X", version)
 }
 
-// naive implementation of tls.CipherSuiteName from Go 1.14
-func cipherSuiteName(id uint16) string {
-	switch id {
-	case 0x0005:
-		return "TLS_RSA_WITH_RC4_128_SHA"
-	case 0x000a:
-		return "TLS_RSA_WITH_3DES_EDE_CBC_SHA"
-	case 0x002f:
-		return "TLS_RSA_WITH_AES_128_CBC_SHA"
-	case 0x0035:
-		return "TLS_RSA_WITH_AES_256_CBC_SHA"
-	case 0x003c:
-		return "TLS_RSA_WITH_AES_128_CBC_SHA256"
-	case 0x009c:
-		return "TLS_RSA_WITH_AES_128_GCM_SHA256"
-	case 0x009d:
-		return "TLS_RSA_WITH_AES_256_GCM_SHA384"
-	case 0xc007:
-		return "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA"
-	case 0xc009:
-		return "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA"
-	case 0xc00a:
-		return "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA"
-	case 0xc011:
-		return "TLS_ECDHE_RSA_WITH_RC4_128_SHA"
-	case 0xc012:
-		return "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA"
-	case 0xc013:
-		return "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"
-	case 0xc014:
-		return "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA"
-	case 0xc023:
-		return "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256"
-	case 0xc027:
-		return "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256"
-	case 0xc02f:
-		return "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
-	case 0xc02b:
-		return "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"
-	case 0xc030:
-		return "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"
-	case 0xc02c:
-		return "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"
-	case 0xcca8:
-		return "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"
-	case 0xcca9:
-		return "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"
-	case 0x1301:
-		return "TLS_AES_128_GCM_SHA256"
-	case 0x1302:
-		return "TLS_AES_256_GCM_SHA384"
-	case 0x1303:
-		return "TLS_CHACHA20_POLY1305_SHA256"
-	case 0x5600:
-		return "TLS_FALLBACK_SCSV"
+func (segs *httpSubsegments) TLSHandshakeDone(state tls.ConnectionState, err error) {
+	conn := segs.connTrace()
+	if conn == nil {
+		return
+	}
+	conn.TLSHandshakeDone(state, err)
+	if err != nil {
+		segs.connFailed(conn)
 	}
-	return fmt.Sprintf("0x%04X", id)
 }
 
 func (segs *httpSubsegments) WroteRequest(info httptrace.WroteRequestInfo) {
@@ -251,25 +124,13 @@ func (segs *httpSubsegments) Cancel() {
 	segs.mu.Lock()
 	defer segs.mu.Unlock()
 
-	if segs.dnsCtx != nil {
-		segs.dnsSeg.AddError(context.Canceled)
-		segs.dnsSeg.Close()
-		segs.dnsCtx, segs.dnsSeg = nil, nil
-	}
-	if segs.dialCtx != nil {
-		segs.dialSeg.AddError(context.Canceled)
-		segs.dialSeg.Close()
-		segs.dialCtx, segs.dialSeg = nil, nil
-	}
-	if segs.tlsCtx != nil {
-		segs.tlsSeg.AddError(context.Canceled)
-		segs.tlsSeg.Close()
-		segs.tlsCtx, segs.tlsSeg = nil, nil
+	if segs.conn != nil {
+		segs.conn.Cancel()
 	}
 	if segs.connCtx != nil {
 		segs.connSeg.AddError(context.Canceled)
 		segs.connSeg.Close()
-		segs.connCtx, segs.connSeg = nil, nil
+		segs.connCtx, segs.connSeg, segs.conn = nil, nil, nil
 	}
 	if segs.reqCtx != nil {
 		segs.reqSeg.AddError(context.Canceled)
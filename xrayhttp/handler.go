@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/shogo82148/aws-xray-yasdk-go/xray"
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
@@ -53,26 +54,41 @@ type httpTracer struct {
 	tn     TracingNamer
 	client *xray.Client
 	h      http.Handler
+	cfg    handlerConfig
 }
 
 // Handler wraps the provided http handler with xray.Capture
-func Handler(tn TracingNamer, h http.Handler) http.Handler {
+func Handler(tn TracingNamer, h http.Handler, opts ...HandlerOption) http.Handler {
 	return &httpTracer{
-		tn: tn,
-		h:  h,
+		tn:  tn,
+		h:   h,
+		cfg: newHandlerConfig(opts),
 	}
 }
 
 // HandlerWithClient wraps the provided http handler with xray.Capture
-func HandlerWithClient(tn TracingNamer, client *xray.Client, h http.Handler) http.Handler {
+func HandlerWithClient(tn TracingNamer, client *xray.Client, h http.Handler, opts ...HandlerOption) http.Handler {
 	return &httpTracer{
 		tn:     tn,
 		client: client,
 		h:      h,
+		cfg:    newHandlerConfig(opts),
 	}
 }
 
 func (tracer *httpTracer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if tracer.cfg.excluded(r) {
+		tracer.h.ServeHTTP(w, r)
+		return
+	}
+
+	reqCtx := r.Context()
+	ctx := reqCtx
+	route := tracer.cfg.extractRoute(tracer.h, r)
+	if route != "" {
+		ctx = withRoute(ctx, route)
+		r = r.WithContext(ctx)
+	}
 	name := tracer.tn.TracingName(r)
 	if name == "" {
 		name = os.Getenv("AWS_XRAY_TRACING_NAME")
@@ -80,30 +96,50 @@ func (tracer *httpTracer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			name = "unknown"
 		}
 	}
-	ctx := r.Context()
 	if tracer.client != nil {
 		ctx = xray.WithClient(ctx, tracer.client)
 	}
-	ctx, seg := xray.BeginSegmentWithRequest(ctx, name, r)
+	ctx, seg := xray.BeginSegmentWithRoute(ctx, name, route, r)
 	r = r.WithContext(ctx)
+	if route != "" {
+		seg.AddAnnotationString("http_route", route)
+	}
 
-	ip, forwarded := clientIP(r)
+	ip, forwarded := tracer.cfg.clientIP(r)
 	requestInfo := &schema.HTTPRequest{
 		Method:        r.Method,
-		URL:           getURL(r),
+		URL:           tracer.cfg.url(r),
 		ClientIP:      ip,
 		XForwardedFor: forwarded,
 		UserAgent:     r.UserAgent(),
 	}
 	seg.SetHTTPRequest(requestInfo)
+	tracer.cfg.beginRequest(r, seg)
+
+	var body *countingBody
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &countingBody{ReadCloser: r.Body}
+		r.Body = body
+	}
 
-	rw := &serverResponseTracer{rw: w, ctx: ctx, seg: seg}
+	rw := &serverResponseTracer{rw: w, ctx: ctx, seg: seg, traceHijack: tracer.cfg.traceHijack}
+	rw.stream.start = time.Now()
 	defer rw.close()
 	tracer.h.ServeHTTP(wrap(rw), r)
 	if rw.hijacked {
 		return
 	}
 
+	tracer.cfg.endRequest(rw.Header(), seg)
+	rw.stream.record(seg)
+	if body != nil {
+		if n := body.count(); n > 0 {
+			seg.AddMetadataToNamespace("http", "request_bytes_read", n)
+		}
+	}
+	if tracer.cfg.abortOnDisconnect {
+		markAborted(reqCtx, seg)
+	}
 	responseInfo := &schema.HTTPResponse{
 		Status:        rw.status,
 		ContentLength: rw.size,
@@ -162,6 +198,12 @@ type serverResponseTracer struct {
 	status   int
 	size     int64
 	hijacked bool
+	stream   streamStats
+
+	// the connection hijacked with WithHijackTracing.
+	hijackedConn *hijackedConn
+
+	traceHijack bool
 }
 
 func (rw *serverResponseTracer) Header() http.Header {
@@ -174,6 +216,7 @@ func (rw *serverResponseTracer) Write(b []byte) (int, error) {
 	}
 	size, err := rw.rw.Write(b)
 	rw.size += int64(size)
+	rw.stream.write(int64(size))
 	return size, err
 }
 
@@ -200,6 +243,9 @@ func (rw *serverResponseTracer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 			ContentLength: rw.size,
 		}
 		rw.seg.SetHTTPResponse(responseInfo)
+		if rw.traceHijack {
+			conn, buf = rw.traceHijackedConn(conn, buf)
+		}
 		rw.close()
 	}
 	return conn, buf, err
@@ -208,6 +254,7 @@ func (rw *serverResponseTracer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 func (rw *serverResponseTracer) Flush() {
 	if f, ok := rw.rw.(http.Flusher); ok {
 		f.Flush()
+		rw.stream.flush()
 	}
 }
 
@@ -232,6 +279,7 @@ func (rw *serverResponseTracer) WriteString(str string) (int, error) {
 		size, err = rw.rw.Write([]byte(str))
 	}
 	rw.size += int64(size)
+	rw.stream.write(int64(size))
 	return size, err
 }
 
@@ -244,6 +292,7 @@ func (rw *serverResponseTracer) ReadFrom(src io.Reader) (int64, error) {
 		size, err = io.Copy(rw.rw, src)
 	}
 	rw.size += size
+	rw.stream.write(size)
 	return size, err
 }
 
@@ -261,6 +310,9 @@ func (rw *serverResponseTracer) close() {
 		rw.seg.Close()
 		rw.ctx, rw.seg = nil, nil
 	}
+	if err != nil && rw.hijackedConn != nil {
+		rw.hijackedConn.abort(err)
+	}
 	if err != nil {
 		panic(err)
 	}
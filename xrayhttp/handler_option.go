@@ -0,0 +1,181 @@
+package xrayhttp
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+// RedactedValue is recorded instead of the values of the redacted query parameters.
+const RedactedValue = "REDACTED"
+
+// HandlerOption is an option for Handler and HandlerWithClient.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	requestHeaders    []string
+	responseHeaders   []string
+	recordQuery       bool
+	redactedParams    map[string]struct{}
+	user              func(r *http.Request) string
+	annotators        []func(r *http.Request, seg *xray.Segment)
+	excludePaths      []string
+	route             bool
+	routeExtractor    func(r *http.Request) string
+	trustedProxies    []*net.IPNet
+	traceHijack       bool
+	abortOnDisconnect bool
+}
+
+// WithRequestHeaders records the request headers in the allowlist
+// as the metadata "request_headers" in the "http" namespace.
+func WithRequestHeaders(names ...string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		for _, name := range names {
+			cfg.requestHeaders = append(cfg.requestHeaders, http.CanonicalHeaderKey(name))
+		}
+	}
+}
+
+// WithResponseHeaders records the response headers in the allowlist
+// as the metadata "response_headers" in the "http" namespace.
+func WithResponseHeaders(names ...string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		for _, name := range names {
+			cfg.responseHeaders = append(cfg.responseHeaders, http.CanonicalHeaderKey(name))
+		}
+	}
+}
+
+// WithQueryString records the query string as a part of the request URL.
+// The values of the parameters named redact are replaced with RedactedValue.
+// By default, the query string is not recorded.
+func WithQueryString(redact ...string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.recordQuery = true
+		if cfg.redactedParams == nil {
+			cfg.redactedParams = make(map[string]struct{}, len(redact))
+		}
+		for _, name := range redact {
+			cfg.redactedParams[name] = struct{}{}
+		}
+	}
+}
+
+// WithUserHeader sets the value of the request header as the user of the segment.
+func WithUserHeader(name string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.user = func(r *http.Request) string {
+			return r.Header.Get(name)
+		}
+	}
+}
+
+// WithUserFunc sets the returned value of f as the user of the segment.
+func WithUserFunc(f func(r *http.Request) string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.user = f
+	}
+}
+
+// WithAnnotator calls f with the segment of each request before the handler is called.
+// It is useful for adding custom annotations.
+func WithAnnotator(f func(r *http.Request, seg *xray.Segment)) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.annotators = append(cfg.annotators, f)
+	}
+}
+
+// WithExcludePaths excludes the requests whose path matches the patterns from tracing, e.g. "/healthz".
+// The patterns may contain the wildcards '*' and '?'.
+func WithExcludePaths(patterns ...string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.excludePaths = append(cfg.excludePaths, patterns...)
+	}
+}
+
+func newHandlerConfig(opts []HandlerOption) handlerConfig {
+	var cfg handlerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+func (cfg *handlerConfig) excluded(r *http.Request) bool {
+	for _, pattern := range cfg.excludePaths {
+		if sampling.WildcardMatch(pattern, r.URL.Path, false) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg *handlerConfig) url(r *http.Request) string {
+	u := getURL(r)
+	if !cfg.recordQuery || r.URL.RawQuery == "" {
+		return u
+	}
+	return u + "?" + redactQuery(r.URL.RawQuery, cfg.redactedParams)
+}
+
+// redactQuery replaces the values of the redacted parameters, keeping the order of the parameters.
+func redactQuery(query string, redacted map[string]struct{}) string {
+	if len(redacted) == 0 {
+		return query
+	}
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key := param
+		if idx := strings.IndexByte(param, '='); idx >= 0 {
+			key = param[:idx]
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			continue
+		}
+		if _, ok := redacted[name]; ok {
+			params[i] = key + "=" + RedactedValue
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+func (cfg *handlerConfig) beginRequest(r *http.Request, seg *xray.Segment) {
+	if cfg.user != nil {
+		if user := cfg.user(r); user != "" {
+			seg.SetUser(user)
+		}
+	}
+	if headers := pickHeaders(r.Header, cfg.requestHeaders); headers != nil {
+		seg.AddMetadataToNamespace("http", "request_headers", headers)
+	}
+	for _, f := range cfg.annotators {
+		f(r, seg)
+	}
+}
+
+func (cfg *handlerConfig) endRequest(header http.Header, seg *xray.Segment) {
+	if headers := pickHeaders(header, cfg.responseHeaders); headers != nil {
+		seg.AddMetadataToNamespace("http", "response_headers", headers)
+	}
+}
+
+func pickHeaders(header http.Header, names []string) map[string]interface{} {
+	var ret map[string]interface{}
+	for _, name := range names {
+		values, ok := header[name]
+		if !ok {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[string]interface{}, len(names))
+		}
+		ret[name] = strings.Join(values, ", ")
+	}
+	return ret
+}
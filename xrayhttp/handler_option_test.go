@@ -0,0 +1,148 @@
+package xrayhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func TestHandler_Options(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(
+		FixedTracingNamer("test"),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Cache", "HIT")
+			w.Header().Set("Set-Cookie", "secret")
+			w.WriteHeader(http.StatusOK)
+		}),
+		WithRequestHeaders("x-request-id", "X-Missing"),
+		WithResponseHeaders("X-Cache"),
+		WithQueryString("token"),
+		WithUserHeader("X-User"),
+		WithAnnotator(func(r *http.Request, seg *xray.Segment) {
+			seg.AddAnnotationString("tenant", r.Header.Get("X-Tenant"))
+		}),
+	)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/foo?q=bar&token=secret&token=secret2", nil)
+	req.Header.Set("X-Request-Id", "request-id")
+	req.Header.Set("X-User", "alice")
+	req.Header.Set("X-Tenant", "tenant-1")
+	req.Header.Set("Authorization", "secret")
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &schema.Segment{
+		Name: "test",
+		User: "alice",
+		HTTP: &schema.HTTP{
+			Request: &schema.HTTPRequest{
+				Method:   http.MethodGet,
+				URL:      "http://example.com/foo?q=bar&token=REDACTED&token=REDACTED",
+				ClientIP: "192.0.2.1",
+			},
+			Response: &schema.HTTPResponse{
+				Status: http.StatusOK,
+			},
+		},
+		Annotations: map[string]interface{}{
+			"tenant": "tenant-1",
+		},
+		Metadata: map[string]interface{}{
+			"http": map[string]interface{}{
+				"request_headers": map[string]interface{}{
+					"X-Request-Id": "request-id",
+				},
+				"response_headers": map[string]interface{}{
+					"X-Cache": "HIT",
+				},
+			},
+		},
+		Subsegments: []*schema.Segment{
+			{Name: "response"},
+		},
+		Service: xray.ServiceData,
+	}
+	if diff := cmp.Diff(want, got, ignoreVariableField); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHandler_UserFunc(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(
+		FixedTracingNamer("test"),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithUserFunc(func(r *http.Request) string {
+			return "user-" + r.URL.Query().Get("id")
+		}),
+	)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/?id=42", nil)
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.User != "user-42" {
+		t.Errorf("want %q, got %q", "user-42", got.User)
+	}
+	// the query string is not recorded by default.
+	if got.HTTP.Request.URL != "http://example.com/" {
+		t.Errorf("want %q, got %q", "http://example.com/", got.HTTP.Request.URL)
+	}
+}
+
+func TestHandler_ExcludePaths(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	var called int
+	h := Handler(
+		FixedTracingNamer("test"),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called++
+			if r.URL.Path == "/healthz" || r.URL.Path == "/internal/status" {
+				if xray.ContextSegment(r.Context()) != nil {
+					t.Error("want no segment for the excluded path")
+				}
+			}
+		}),
+		WithExcludePaths("/healthz", "/internal/*"),
+	)
+
+	for _, path := range []string{"/healthz", "/internal/status", "/foo"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+		req = req.WithContext(ctx)
+		h.ServeHTTP(rec, req)
+	}
+	if called != 3 {
+		t.Errorf("want the handler called 3 times, got %d", called)
+	}
+
+	// the first segment is for "/foo", because the excluded paths are not traced.
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.HTTP.Request.URL != "http://example.com/foo" {
+		t.Errorf("want %q, got %q", "http://example.com/foo", got.HTTP.Request.URL)
+	}
+}
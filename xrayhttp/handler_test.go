@@ -221,6 +221,11 @@ func TestHandler_ReadFrom(t *testing.T) {
 				ContentLength: 5,
 			},
 		},
+		Metadata: map[string]interface{}{
+			"http": map[string]interface{}{
+				"flush_count": 1.0,
+			},
+		},
 		Subsegments: []*schema.Segment{
 			{Name: "response"},
 		},
@@ -0,0 +1,107 @@
+package xrayhttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+// WithHijackTracing traces the connections hijacked by the handler, e.g. WebSocket.
+// The "websocket" subsegment is kept open until the connection is closed,
+// and it records the bytes read and written, and the error of closing.
+// The segment of the request is also kept open, so the handler must close the hijacked connection,
+// otherwise the segment is never emitted.
+// If the handler panics after hijacking, the segment is closed as a fault, but the connection is left open.
+func WithHijackTracing() HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.traceHijack = true
+	}
+}
+
+// BeginMessage begins a subsegment for a message on the connection hijacked with WithHijackTracing.
+// If the connection is not traced, it returns a dummy segment.
+//
+// Caller should close the subsegment when the message is handled.
+func BeginMessage(conn net.Conn, name string) (context.Context, *xray.Segment) {
+	c, ok := conn.(*hijackedConn)
+	if !ok {
+		return xray.BeginDummySegment(context.Background())
+	}
+	return xray.BeginSubsegment(c.ctx, name)
+}
+
+type hijackedConn struct {
+	net.Conn
+	ctx  context.Context
+	seg  *xray.Segment
+	root *xray.Segment
+
+	bytesRead    int64 // accessed atomically
+	bytesWritten int64 // accessed atomically
+	closeOnce    sync.Once
+}
+
+// traceHijackedConn wraps the hijacked connection.
+// The segment of the request is closed with the connection, instead of the end of the handler.
+func (rw *serverResponseTracer) traceHijackedConn(conn net.Conn, buf *bufio.ReadWriter) (net.Conn, *bufio.ReadWriter) {
+	ctx, seg := xray.BeginSubsegment(rw.ctx, "websocket")
+	c := &hijackedConn{
+		Conn: conn,
+		ctx:  ctx,
+		seg:  seg,
+		root: rw.seg,
+	}
+	rw.ctx, rw.seg = nil, nil
+	rw.hijackedConn = c
+
+	// rebuild the buffer around the wrapped connection, keeping the buffered data.
+	var r io.Reader = c
+	if n := buf.Reader.Buffered(); n > 0 {
+		data, _ := buf.Reader.Peek(n)
+		r = io.MultiReader(bytes.NewReader(append([]byte(nil), data...)), c)
+	}
+	buf.Writer.Flush()
+	return c, bufio.NewReadWriter(bufio.NewReader(r), bufio.NewWriter(c))
+}
+
+func (c *hijackedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.bytesRead, int64(n))
+	return n, err
+}
+
+func (c *hijackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.bytesWritten, int64(n))
+	return n, err
+}
+
+func (c *hijackedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		c.seg.AddMetadata("bytes_read", atomic.LoadInt64(&c.bytesRead))
+		c.seg.AddMetadata("bytes_written", atomic.LoadInt64(&c.bytesWritten))
+		c.seg.AddError(err)
+		c.seg.Close()
+		c.root.Close()
+	})
+	return err
+}
+
+// abort closes the segments as faults, when the handler panics after hijacking.
+func (c *hijackedConn) abort(err interface{}) {
+	c.closeOnce.Do(func() {
+		c.seg.AddMetadata("bytes_read", atomic.LoadInt64(&c.bytesRead))
+		c.seg.AddMetadata("bytes_written", atomic.LoadInt64(&c.bytesWritten))
+		c.seg.SetFault()
+		c.seg.Close()
+		c.root.AddPanic(err)
+		c.root.Close()
+	})
+}
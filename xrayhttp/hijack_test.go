@@ -0,0 +1,164 @@
+package xrayhttp
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func TestHandler_HijackTracing(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	client := xray.ContextClient(ctx)
+	h := HandlerWithClient(FixedTracingNamer("test"), client, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		if _, err := buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n"); err != nil {
+			panic(err)
+		}
+		if err := buf.Flush(); err != nil {
+			panic(err)
+		}
+
+		line, err := buf.ReadString('\n')
+		if err != nil {
+			panic(err)
+		}
+		_, seg := BeginMessage(conn, "message")
+		defer seg.Close()
+		if _, err := conn.Write([]byte(strings.ToUpper(line))); err != nil {
+			panic(err)
+		}
+	}), WithHijackTracing())
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("GET /hijack HTTP/1.1\r\nHost: example.com\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("want %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "PING\n" {
+		t.Errorf("want %q, got %q", "PING\n", line)
+	}
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &schema.Segment{
+		Name: "test",
+		HTTP: &schema.HTTP{
+			Request: &schema.HTTPRequest{
+				Method:   http.MethodGet,
+				URL:      "http://example.com/hijack",
+				ClientIP: "127.0.0.1",
+			},
+			Response: &schema.HTTPResponse{
+				Status: http.StatusSwitchingProtocols,
+			},
+		},
+		Subsegments: []*schema.Segment{
+			{
+				Name: "websocket",
+				Metadata: map[string]interface{}{
+					"default": map[string]interface{}{
+						"bytes_read":    float64(len("ping\n")),
+						"bytes_written": float64(len("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\nPING\n")),
+					},
+				},
+				Subsegments: []*schema.Segment{
+					{Name: "message"},
+				},
+			},
+		},
+		Service: xray.ServiceData,
+	}
+	if diff := cmp.Diff(want, got, ignoreVariableField); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBeginMessage_NotTraced(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	ctx, seg := BeginMessage(server, "message")
+	if seg != nil {
+		t.Error("want nil segment")
+	}
+	if xray.ContextSegment(ctx) != nil {
+		t.Error("want no segment in the context")
+	}
+	seg.Close()
+}
+
+func TestHandler_HijackTracingPanic(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	client := xray.ContextClient(ctx)
+	h := HandlerWithClient(FixedTracingNamer("test"), client, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			panic(err)
+		}
+		// the handler panics without closing the connection.
+		panic("BOOM!")
+	}), WithHijackTracing())
+	ts := httptest.NewUnstartedServer(h)
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.Start()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("GET /hijack HTTP/1.1\r\nHost: example.com\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Fault || got.Cause == nil {
+		t.Errorf("want fault with the cause, got %#v", got)
+	}
+	if len(got.Subsegments) != 1 || got.Subsegments[0].Name != "websocket" || !got.Subsegments[0].Fault {
+		t.Errorf("want the websocket subsegment as a fault, got %#v", got.Subsegments)
+	}
+}
@@ -0,0 +1,268 @@
+package xrayhttp
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+const (
+	defaultMaxRetries    = 3
+	defaultMaxRetryAfter = time.Minute
+	maxDrainBytes        = 4096
+)
+
+// RetryOption is an option for RetryRoundTripper.
+type RetryOption func(*retryConfig)
+
+type retryConfig struct {
+	maxRetries    int
+	maxRetryAfter time.Duration
+	backoff       func(retry int) time.Duration
+	statuses      map[int]struct{}
+	retryable     RequestFilter
+}
+
+type attemptContextKeyType struct{}
+
+// the number of the attempt, it makes the roundtripper record the request as an "attempt" subsegment.
+var attemptContextKey = &attemptContextKeyType{}
+
+// WithMaxRetries configures the maximum number of retries. The default is 3.
+func WithMaxRetries(n int) RetryOption {
+	return func(cfg *retryConfig) {
+		cfg.maxRetries = n
+	}
+}
+
+// WithBackoff configures the delay before the retry. retry starts with 1.
+// The default is ExponentialBackoff(100*time.Millisecond, 5*time.Second).
+func WithBackoff(f func(retry int) time.Duration) RetryOption {
+	return func(cfg *retryConfig) {
+		cfg.backoff = f
+	}
+}
+
+// WithMaxRetryAfter configures the maximum delay that the Retry-After header can ask for. The default is 1 minute.
+// If the server asks to wait longer, the response is returned without retrying.
+func WithMaxRetryAfter(d time.Duration) RetryOption {
+	return func(cfg *retryConfig) {
+		cfg.maxRetryAfter = d
+	}
+}
+
+// WithRetryableStatuses configures the status codes to retry.
+// The default is 429 Too Many Requests, 502 Bad Gateway, 503 Service Unavailable and 504 Gateway Timeout.
+func WithRetryableStatuses(codes ...int) RetryOption {
+	return func(cfg *retryConfig) {
+		cfg.statuses = make(map[int]struct{}, len(codes))
+		for _, code := range codes {
+			cfg.statuses[code] = struct{}{}
+		}
+	}
+}
+
+// WithRetryableRequests configures the requests to retry.
+// The default is IdempotentRequest, because retrying the other requests may repeat their side effects.
+func WithRetryableRequests(f RequestFilter) RetryOption {
+	return func(cfg *retryConfig) {
+		cfg.retryable = f
+	}
+}
+
+// IdempotentRequest reports whether the request is idempotent.
+// The requests with the methods GET, HEAD, OPTIONS, TRACE, PUT and DELETE,
+// or with the Idempotency-Key or X-Idempotency-Key header are idempotent.
+func IdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
+
+// ExponentialBackoff returns the backoff that doubles the delay from base up to max.
+func ExponentialBackoff(base, max time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		d := base
+		for i := 1; i < retry && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// RetryRoundTripper wraps the provided http roundtripper with retries.
+// The roundtripper is also wrapped with xrayhttp.RoundTripper if it is not yet.
+// The logical call is recorded as the remote subsegment named after the host,
+// and each attempt is recorded as an "attempt" subsegment under it.
+// The number of retries and the backoff delays are recorded in the metadata.
+//
+// Only the idempotent requests are retried by default, see WithRetryableRequests.
+// The requests with a body are retried only if their GetBody is available.
+// Transport errors are retried unless the context of the request is done.
+// The Retry-After header of the response overrides the backoff, up to WithMaxRetryAfter.
+func RetryRoundTripper(rt http.RoundTripper, opts ...RetryOption) http.RoundTripper {
+	cfg := retryConfig{
+		maxRetries:    defaultMaxRetries,
+		maxRetryAfter: defaultMaxRetryAfter,
+		backoff:       ExponentialBackoff(100*time.Millisecond, 5*time.Second),
+		statuses: map[int]struct{}{
+			http.StatusTooManyRequests:    {},
+			http.StatusBadGateway:         {},
+			http.StatusServiceUnavailable: {},
+			http.StatusGatewayTimeout:     {},
+		},
+		retryable: IdempotentRequest,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &retryRoundTripper{
+		base: RoundTripper(rt).(*roundtripper),
+		cfg:  cfg,
+	}
+}
+
+type retryRoundTripper struct {
+	base *roundtripper
+	cfg  retryConfig
+}
+
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	var seg *xray.Segment
+	if rt.base.cfg.shouldTrace(req) {
+		name, isEmptyHost := rt.base.remoteName(req)
+		ctx, seg = xray.BeginSubsegment(ctx, name)
+		defer seg.Close()
+		if !isEmptyHost {
+			seg.SetNamespace("remote")
+		}
+		seg.SetHTTPRequest(&schema.HTTPRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Traced: rt.base.cfg.downstreamTraced(req),
+		})
+	}
+
+	var backoff []float64
+	for retry := 0; ; retry++ {
+		resp, err := rt.attempt(ctx, req, retry)
+		retryable := retry < rt.cfg.maxRetries && rt.shouldRetry(ctx, req, resp, err)
+		var delay time.Duration
+		if retryable {
+			delay, retryable = rt.delay(retry, resp)
+		}
+		if !retryable {
+			seg.AddMetadataToNamespace("http", "retries", retry)
+			if len(backoff) > 0 {
+				seg.AddMetadataToNamespace("http", "backoff", backoff)
+			}
+			if err != nil {
+				seg.AddError(err)
+			} else if seg != nil {
+				setHTTPResponse(seg, resp)
+			}
+			return resp, err
+		}
+
+		if resp != nil {
+			// drain the body for reusing the connection.
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+			resp.Body.Close()
+		}
+
+		backoff = append(backoff, delay.Seconds())
+		if err := sleep(ctx, delay); err != nil {
+			seg.AddMetadataToNamespace("http", "retries", retry)
+			seg.AddMetadataToNamespace("http", "backoff", backoff)
+			seg.AddError(err)
+			return nil, err
+		}
+	}
+}
+
+func (rt *retryRoundTripper) attempt(ctx context.Context, req *http.Request, retry int) (*http.Response, error) {
+	r := req.WithContext(context.WithValue(ctx, attemptContextKey, retry+1))
+	if retry > 0 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return rt.base.RoundTrip(r)
+}
+
+func (rt *retryRoundTripper) shouldRetry(ctx context.Context, req *http.Request, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if !rt.cfg.retryable(req) {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// the body can't be replayed.
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	_, ok := rt.cfg.statuses[resp.StatusCode]
+	return ok
+}
+
+// delay returns the delay before the next retry.
+// It returns false if the server asks to wait longer than the limit.
+func (rt *retryRoundTripper) delay(retry int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if d, ok := retryAfter(resp); ok {
+			return d, d <= rt.cfg.maxRetryAfter
+		}
+	}
+	return rt.cfg.backoff(retry + 1), true
+}
+
+// retryAfter parses the Retry-After header, which is the delay in seconds or the HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,381 @@
+package xrayhttp
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if got := backoff(i + 1); got != w {
+			t.Errorf("retry %d: want %s, got %s", i+1, w, got)
+		}
+	}
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		if string(body) != "hello" {
+			t.Errorf("want %q, got %q", "hello", string(body))
+		}
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: RetryRoundTripper(nil, WithBackoff(func(retry int) time.Duration {
+			return time.Duration(retry) * time.Millisecond
+		})),
+	}
+	func() {
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodPut, ts.URL, strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(ctx)
+		req.Host = "example.com"
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("want %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Subsegments) != 1 {
+		t.Fatalf("want 1 subsegment, got %d", len(got.Subsegments))
+	}
+	call := got.Subsegments[0]
+	if call.Name != "example.com" {
+		t.Errorf("want %q, got %q", "example.com", call.Name)
+	}
+	if call.Namespace != "remote" {
+		t.Errorf("want %q, got %q", "remote", call.Namespace)
+	}
+	if call.HTTP.Response.Status != http.StatusOK {
+		t.Errorf("want %d, got %d", http.StatusOK, call.HTTP.Response.Status)
+	}
+	wantMetadata := map[string]interface{}{
+		"http": map[string]interface{}{
+			"retries": 2.0,
+			"backoff": []interface{}{0.001, 0.002},
+		},
+	}
+	if diff := cmp.Diff(wantMetadata, call.Metadata); diff != "" {
+		t.Errorf("metadata mismatch (-want +got):\n%s", diff)
+	}
+	if len(call.Subsegments) != 3 {
+		t.Fatalf("want 3 attempts, got %d", len(call.Subsegments))
+	}
+	for i, attempt := range call.Subsegments {
+		if attempt.Name != "attempt" {
+			t.Errorf("want %q, got %q", "attempt", attempt.Name)
+		}
+		if attempt.Namespace != "" {
+			t.Errorf("attempt %d: want no namespace, got %q", i+1, attempt.Namespace)
+		}
+		if got := attempt.Metadata["http"].(map[string]interface{})["attempt"]; got != float64(i+1) {
+			t.Errorf("attempt %d: unexpected metadata %v", i+1, got)
+		}
+		for _, sub := range attempt.Subsegments {
+			if sub.Namespace == "remote" {
+				t.Errorf("attempt %d: unexpected remote subsegment %q", i+1, sub.Name)
+			}
+		}
+		status := attempt.HTTP.Response.Status
+		wantStatus := http.StatusServiceUnavailable
+		if i == 2 {
+			wantStatus = http.StatusOK
+		}
+		if status != wantStatus {
+			t.Errorf("attempt %d: want %d, got %d", i+1, wantStatus, status)
+		}
+	}
+}
+
+func TestRetryRoundTripper_TracingNamer(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	rt := RoundTripper(nil, WithTracingNamer(TracingNamerFunc(func(req *http.Request) string {
+		return "my-service"
+	})))
+	client := &http.Client{
+		Transport: RetryRoundTripper(rt),
+	}
+	func() {
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := got.Subsegments[0].Name; name != "my-service" {
+		t.Errorf("want %q, got %q", "my-service", name)
+	}
+}
+
+func TestRetryRoundTripper_Idempotent(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	noBackoff := WithBackoff(func(retry int) time.Duration { return 0 })
+	do := func(rt http.RoundTripper, header string) int32 {
+		atomic.StoreInt32(&count, 0)
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set(header, "key")
+		}
+		resp, err := (&http.Client{Transport: rt}).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return atomic.LoadInt32(&count)
+	}
+
+	// POST is not idempotent.
+	if got := do(RetryRoundTripper(nil, WithMaxRetries(1), noBackoff), ""); got != 1 {
+		t.Errorf("want 1 attempt, got %d", got)
+	}
+
+	// the idempotency key makes the request idempotent.
+	if got := do(RetryRoundTripper(nil, WithMaxRetries(1), noBackoff), "Idempotency-Key"); got != 2 {
+		t.Errorf("want 2 attempts, got %d", got)
+	}
+
+	// retry all requests.
+	all := WithRetryableRequests(func(req *http.Request) bool { return true })
+	if got := do(RetryRoundTripper(nil, WithMaxRetries(1), noBackoff, all), ""); got != 2 {
+		t.Errorf("want 2 attempts, got %d", got)
+	}
+}
+
+func TestRetryRoundTripper_RetryAfter(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer ts.Close()
+
+	// Retry-After overrides the backoff.
+	client := &http.Client{
+		Transport: RetryRoundTripper(nil, WithBackoff(func(retry int) time.Duration {
+			return time.Hour
+		})),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("want %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestRetryRoundTripper_RetryAfterTooLong(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	// the server asks to wait longer than the limit, so give up without retrying.
+	client := &http.Client{
+		Transport: RetryRoundTripper(nil, WithMaxRetryAfter(time.Second)),
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("want %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	if count != 1 {
+		t.Errorf("want 1 attempt, got %d", count)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, true}, // in the past
+		{"invalid", 0, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		got, ok := retryAfter(resp)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%q: want (%s, %t), got (%s, %t)", tt.header, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestRetryRoundTripper_GiveUp(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: RetryRoundTripper(nil, WithMaxRetries(2), WithBackoff(func(retry int) time.Duration {
+			return 0
+		})),
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("want %d, got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+	if count != 3 {
+		t.Errorf("want 3 attempts, got %d", count)
+	}
+}
+
+func TestRetryRoundTripper_NotRetryable(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: RetryRoundTripper(nil, WithRetryableStatuses(http.StatusTooManyRequests)),
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// the body can't be replayed.
+	req, err := http.NewRequest(http.MethodPost, ts.URL, ioutil.NopCloser(strings.NewReader("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Transport = RetryRoundTripper(nil, WithRetryableRequests(func(req *http.Request) bool { return true }))
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if count != 2 {
+		t.Errorf("want 2 attempts, got %d", count)
+	}
+}
+
+func TestRetryRoundTripper_Canceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: RetryRoundTripper(nil, WithBackoff(func(retry int) time.Duration {
+			return time.Hour
+		})),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(ctx)
+	if _, err := client.Do(req); err == nil {
+		t.Error("want error, got nil")
+	}
+}
@@ -0,0 +1,89 @@
+package xrayhttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type routeContextKeyType struct{}
+
+var routeContextKey = &routeContextKeyType{}
+
+// ContextRoute returns the route of the request, e.g. "/users/{id}".
+// It is available in TracingNamer and the handlers wrapped by Handler with WithRoute or WithRouteExtractor.
+func ContextRoute(ctx context.Context) string {
+	route, _ := ctx.Value(routeContextKey).(string)
+	return route
+}
+
+func withRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeContextKey, route)
+}
+
+// WithRoute uses the ServeMux pattern that matches the request as the route.
+// The route is recorded as the annotation "http_route", and used as the URL for sampling.
+// With Go 1.22 or later, http.Request.Pattern is used if it is available.
+// Otherwise, the pattern is looked up if the wrapped handler is *http.ServeMux.
+func WithRoute() HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.routeExtractor = nil
+		cfg.route = true
+	}
+}
+
+// WithRouteExtractor is same as WithRoute, but the route is extracted by f.
+func WithRouteExtractor(f func(r *http.Request) string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.routeExtractor = f
+		cfg.route = true
+	}
+}
+
+func (cfg *handlerConfig) extractRoute(h http.Handler, r *http.Request) string {
+	if !cfg.route {
+		return ""
+	}
+	if cfg.routeExtractor != nil {
+		return cfg.routeExtractor(r)
+	}
+	if pattern := requestPattern(r); pattern != "" {
+		return routeFromPattern(pattern)
+	}
+	if mux, ok := h.(*http.ServeMux); ok {
+		_, pattern := mux.Handler(r)
+		return routeFromPattern(pattern)
+	}
+	return ""
+}
+
+// routeFromPattern strips the method and the host from the ServeMux pattern.
+// e.g. "GET example.com/users/{id}" is converted into "/users/{id}".
+func routeFromPattern(pattern string) string {
+	if idx := strings.IndexAny(pattern, " \t"); idx >= 0 {
+		pattern = strings.TrimLeft(pattern[idx:], " \t")
+	}
+	if idx := strings.IndexByte(pattern, '/'); idx > 0 {
+		pattern = pattern[idx:]
+	}
+	return pattern
+}
+
+// RouteTracingNamer names the segments by the name and the route of the request, e.g. "myApp /users/{id}".
+// The characters that are not allowed in the segment names, e.g. '{' and '}', are removed.
+// If the route is not available, only the name is used.
+type RouteTracingNamer struct {
+	Name string
+}
+
+// TracingName implements TracingNamer.
+func (tn RouteTracingNamer) TracingName(r *http.Request) string {
+	route := ContextRoute(r.Context())
+	if route == "" {
+		return tn.Name
+	}
+	if tn.Name == "" {
+		return route
+	}
+	return tn.Name + " " + route
+}
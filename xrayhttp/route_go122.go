@@ -0,0 +1,10 @@
+//go:build go1.22
+// +build go1.22
+
+package xrayhttp
+
+import "net/http"
+
+func requestPattern(r *http.Request) string {
+	return r.Pattern
+}
@@ -0,0 +1,39 @@
+//go:build go1.22
+// +build go1.22
+
+package xrayhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestHandler_RoutePattern(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(RouteTracingNamer{Name: "test"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), WithRoute())
+
+	// the pattern is set by ServeMux.
+	// it is set manually here, because go.mod of this module disables the enhanced patterns of ServeMux.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users/123", nil)
+	req.Pattern = "GET /users/{id}"
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// '{' and '}' are not allowed in the segment names.
+	if got.Name != "test /users/id" {
+		t.Errorf("want %q, got %q", "test /users/id", got.Name)
+	}
+	if got.Annotations["http_route"] != "/users/{id}" {
+		t.Errorf("want %q, got %v", "/users/{id}", got.Annotations["http_route"])
+	}
+}
@@ -0,0 +1,129 @@
+package xrayhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/sampling"
+)
+
+var _ TracingNamer = RouteTracingNamer{}
+
+func TestRouteFromPattern(t *testing.T) {
+	tc := []struct {
+		in   string
+		want string
+	}{
+		{in: "/users/", want: "/users/"},
+		{in: "GET /users/{id}", want: "/users/{id}"},
+		{in: "example.com/users/{id}", want: "/users/{id}"},
+		{in: "POST  example.com/users/{id...}", want: "/users/{id...}"},
+		{in: "", want: ""},
+	}
+	for _, tt := range tc {
+		got := routeFromPattern(tt.in)
+		if got != tt.want {
+			t.Errorf("%q: want %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestHandler_RouteServeMux(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		if route := ContextRoute(r.Context()); route != "/users/" {
+			t.Errorf("want %q, got %q", "/users/", route)
+		}
+	})
+	h := Handler(RouteTracingNamer{Name: "test"}, mux, WithRoute())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users/123", nil)
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "test /users/" {
+		t.Errorf("want %q, got %q", "test /users/", got.Name)
+	}
+	if got.Annotations["http_route"] != "/users/" {
+		t.Errorf("want %q, got %v", "/users/", got.Annotations["http_route"])
+	}
+	if got.HTTP.Request.URL != "http://example.com/users/123" {
+		t.Errorf("want %q, got %q", "http://example.com/users/123", got.HTTP.Request.URL)
+	}
+}
+
+type recordingStrategy struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (s *recordingStrategy) ShouldTrace(req *sampling.Request) *sampling.Decision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.urls = append(s.urls, req.URL)
+	return &sampling.Decision{Sample: false}
+}
+
+func TestHandler_RouteSampling(t *testing.T) {
+	strategy := &recordingStrategy{}
+	client := xray.New(&xray.Config{
+		SamplingStrategy: strategy,
+	})
+	defer client.Close()
+
+	h := HandlerWithClient(
+		FixedTracingNamer("test"),
+		client,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithRouteExtractor(func(r *http.Request) string {
+			return "/users/{id}"
+		}),
+	)
+	for _, path := range []string{"/users/123", "/users/456"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+		h.ServeHTTP(rec, req)
+	}
+
+	strategy.mu.Lock()
+	defer strategy.mu.Unlock()
+	if len(strategy.urls) != 2 || strategy.urls[0] != "/users/{id}" || strategy.urls[1] != "/users/{id}" {
+		t.Errorf("want the route used for sampling, got %v", strategy.urls)
+	}
+}
+
+func TestHandler_NoRoute(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {})
+	h := Handler(RouteTracingNamer{Name: "test"}, mux)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users/123", nil)
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "test" {
+		t.Errorf("want %q, got %q", "test", got.Name)
+	}
+	if _, ok := got.Annotations["http_route"]; ok {
+		t.Error("want no route annotation")
+	}
+}
@@ -0,0 +1,60 @@
+package xrayhttp
+
+import (
+	"context"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+// WithAbortOnDisconnect marks the segment as aborted when the client disconnects before the handler returns.
+// The aborted segment has the annotation "aborted" and the error flag.
+func WithAbortOnDisconnect() HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.abortOnDisconnect = true
+	}
+}
+
+// streamStats is the timing of writes to the response, for streaming responses such as SSE.
+type streamStats struct {
+	start      time.Time
+	firstWrite time.Time
+	lastWrite  time.Time
+	flushes    int
+}
+
+func (s *streamStats) write(n int64) {
+	if n <= 0 {
+		return
+	}
+	now := time.Now()
+	if s.firstWrite.IsZero() {
+		s.firstWrite = now
+	}
+	s.lastWrite = now
+}
+
+func (s *streamStats) flush() {
+	s.flushes++
+}
+
+// record adds the timing to the metadata.
+// The timing is recorded for the responses that wrote something, and flush_count is recorded if it is flushed.
+func (s *streamStats) record(seg *xray.Segment) {
+	if s.flushes > 0 {
+		seg.AddMetadataToNamespace("http", "flush_count", s.flushes)
+	}
+	if s.firstWrite.IsZero() {
+		return
+	}
+	seg.AddMetadataToNamespace("http", "time_to_first_byte", s.firstWrite.Sub(s.start).Seconds())
+	seg.AddMetadataToNamespace("http", "time_to_last_write", s.lastWrite.Sub(s.start).Seconds())
+}
+
+func markAborted(ctx context.Context, seg *xray.Segment) {
+	if ctx.Err() != context.Canceled {
+		return
+	}
+	seg.AddAnnotationBool("aborted", true)
+	seg.SetError()
+}
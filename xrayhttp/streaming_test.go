@@ -0,0 +1,172 @@
+package xrayhttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestHandler_Streaming(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(FixedTracingNamer("test"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, ok := got.Metadata["http"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("want metadata in the http namespace, got %v", got.Metadata)
+	}
+	if md["flush_count"] != float64(3) {
+		t.Errorf("want %d, got %v", 3, md["flush_count"])
+	}
+	ttfb, ok := md["time_to_first_byte"].(float64)
+	if !ok || ttfb < 0 {
+		t.Errorf("invalid time_to_first_byte: %v", md["time_to_first_byte"])
+	}
+	last, ok := md["time_to_last_write"].(float64)
+	if !ok || last < ttfb {
+		t.Errorf("invalid time_to_last_write: %v", md["time_to_last_write"])
+	}
+	if got.HTTP.Response.ContentLength != int64(len("data: 0\n\n")*3) {
+		t.Errorf("want %d, got %d", len("data: 0\n\n")*3, got.HTTP.Response.ContentLength)
+	}
+}
+
+func TestHandler_FlushOnce(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(FixedTracingNamer("test"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+		// flush at the end of the response.
+		w.(http.Flusher).Flush()
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, ok := got.Metadata["http"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("want metadata in the http namespace, got %v", got.Metadata)
+	}
+	if md["flush_count"] != float64(1) {
+		t.Errorf("want %d, got %v", 1, md["flush_count"])
+	}
+	ttfb, ok := md["time_to_first_byte"].(float64)
+	if !ok || ttfb < 0 {
+		t.Errorf("invalid time_to_first_byte: %v", md["time_to_first_byte"])
+	}
+	if last, ok := md["time_to_last_write"].(float64); !ok || last < ttfb {
+		t.Errorf("invalid time_to_last_write: %v", md["time_to_last_write"])
+	}
+}
+
+func TestHandler_NoFlush(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(FixedTracingNamer("test"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, ok := got.Metadata["http"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("want metadata in the http namespace, got %v", got.Metadata)
+	}
+	if _, ok := md["flush_count"]; ok {
+		t.Errorf("want no flush_count, got %v", md["flush_count"])
+	}
+	ttfb, ok := md["time_to_first_byte"].(float64)
+	if !ok || ttfb < 0 {
+		t.Errorf("invalid time_to_first_byte: %v", md["time_to_first_byte"])
+	}
+	if last, ok := md["time_to_last_write"].(float64); !ok || last < ttfb {
+		t.Errorf("invalid time_to_last_write: %v", md["time_to_last_write"])
+	}
+}
+
+func TestHandler_NoWrite(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(FixedTracingNamer("test"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req = req.WithContext(ctx)
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata != nil {
+		t.Errorf("want no metadata, got %v", got.Metadata)
+	}
+}
+
+func TestHandler_AbortOnDisconnect(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	h := Handler(FixedTracingNamer("test"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: 0\n\n")
+		w.(http.Flusher).Flush()
+		// the client disconnects.
+		<-r.Context().Done()
+	}), WithAbortOnDisconnect())
+
+	ctx, cancel := context.WithCancel(ctx)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req = req.WithContext(ctx)
+	cancel()
+	h.ServeHTTP(rec, req)
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Annotations["aborted"] != true {
+		t.Errorf("want aborted, got %v", got.Annotations)
+	}
+	if !got.Error {
+		t.Error("want error, got not")
+	}
+}
@@ -0,0 +1,78 @@
+// Package xraynet traces the raw network connections that don't speak HTTP, e.g. Redis, Memcached and custom TCP protocols.
+//
+//	dialer := &xraynet.Dialer{}
+//	conn, err := dialer.DialContext(ctx, "tcp", "redis.example.com:6379")
+package xraynet
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+// Conn is a traced net.Conn.
+// It records the bytes read and written in the subsegment or the segment of the connection,
+// and closes it when the connection is closed.
+type Conn struct {
+	net.Conn
+	ctx context.Context
+	seg *xray.Segment
+
+	bytesRead    int64 // accessed atomically
+	bytesWritten int64 // accessed atomically
+	closeOnce    sync.Once
+}
+
+// NewConn wraps conn, and begins the subsegment named name under the segment of ctx.
+// The subsegment is closed when the connection is closed.
+func NewConn(ctx context.Context, conn net.Conn, name string) *Conn {
+	ctx, seg := xray.BeginSubsegment(ctx, name)
+	seg.SetNamespace("remote")
+	return newConn(ctx, seg, conn)
+}
+
+func newConn(ctx context.Context, seg *xray.Segment, conn net.Conn) *Conn {
+	seg.AddMetadataToNamespace("net", "network", conn.LocalAddr().Network())
+	seg.AddMetadataToNamespace("net", "local_address", conn.LocalAddr().String())
+	seg.AddMetadataToNamespace("net", "remote_address", conn.RemoteAddr().String())
+	return &Conn{
+		Conn: conn,
+		ctx:  ctx,
+		seg:  seg,
+	}
+}
+
+// Context returns the context of the connection.
+// Use it for creating subsegments, e.g. for each command on the connection.
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Read implements net.Conn.
+func (c *Conn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.bytesRead, int64(n))
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *Conn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.bytesWritten, int64(n))
+	return n, err
+}
+
+// Close closes the connection, and its subsegment or segment.
+func (c *Conn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		c.seg.AddMetadataToNamespace("net", "bytes_read", atomic.LoadInt64(&c.bytesRead))
+		c.seg.AddMetadataToNamespace("net", "bytes_written", atomic.LoadInt64(&c.bytesWritten))
+		c.seg.AddError(err)
+		c.seg.Close()
+	})
+	return err
+}
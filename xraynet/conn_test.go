@@ -0,0 +1,127 @@
+package xraynet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func ignoreVariableFieldFunc(in *schema.Segment) *schema.Segment {
+	out := *in
+	out.ID = ""
+	out.TraceID = ""
+	out.ParentID = ""
+	out.StartTime = 0
+	out.EndTime = 0
+	out.Subsegments = nil
+	if out.AWS != nil {
+		delete(out.AWS, "xray")
+		if len(out.AWS) == 0 {
+			out.AWS = nil
+		}
+	}
+	if out.Cause != nil {
+		for i := range out.Cause.Exceptions {
+			out.Cause.Exceptions[i].ID = ""
+		}
+	}
+	for _, sub := range in.Subsegments {
+		out.Subsegments = append(out.Subsegments, ignoreVariableFieldFunc(sub))
+	}
+	return &out
+}
+
+// some fields change every execution, ignore them.
+var ignoreVariableField = cmp.Transformer("Segment", ignoreVariableFieldFunc)
+
+func TestConn(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	server, client := net.Pipe()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(server, buf); err != nil {
+			panic(err)
+		}
+		if _, err := server.Write([]byte("PONG\n")); err != nil {
+			panic(err)
+		}
+	}()
+
+	func() {
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+
+		conn := NewConn(ctx, client, "memcached")
+		if _, err := conn.Write([]byte("PING")); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != "PONG\n" {
+			t.Errorf("want %q, got %q", "PONG\n", string(buf))
+		}
+		_, seg := xray.BeginSubsegment(conn.Context(), "command")
+		seg.Close()
+		if err := conn.Close(); err != nil {
+			t.Fatal(err)
+		}
+		// closing twice doesn't record twice.
+		conn.Close()
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &schema.Segment{
+		Name: "test",
+		Subsegments: []*schema.Segment{
+			{
+				Name:      "memcached",
+				Namespace: "remote",
+				Metadata: map[string]interface{}{
+					"net": map[string]interface{}{
+						"network":        "pipe",
+						"local_address":  "pipe",
+						"remote_address": "pipe",
+						"bytes_read":     5.0,
+						"bytes_written":  4.0,
+					},
+				},
+				Subsegments: []*schema.Segment{
+					{Name: "command"},
+				},
+			},
+		},
+		Service: xray.ServiceData,
+	}
+	if diff := cmp.Diff(want, got, ignoreVariableField); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConn_Untraced(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	// without any segments, the connection still works.
+	conn := NewConn(context.Background(), client, "memcached")
+	go server.Write([]byte("PONG"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
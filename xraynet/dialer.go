@@ -0,0 +1,97 @@
+package xraynet
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/internal/nettrace"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+// Dialer is a traced net.Dialer.
+// DialContext begins the subsegment named after the host, which lasts until the connection is closed,
+// and records the "connect" subsegment with the "dns", "dial" and "tls" subsegments under it.
+type Dialer struct {
+	// Dialer is the underlying dialer. If it is nil, the zero value of net.Dialer is used.
+	Dialer *net.Dialer
+
+	// TLSConfig is the configuration of TLS.
+	// If it is not nil, DialContext performs the TLS handshake and returns the TLS connection.
+	TLSConfig *tls.Config
+}
+
+// Dial connects to the address on the named network.
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network using the provided context.
+// The returned connection is *Conn.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	ctx, seg := xray.BeginSubsegment(ctx, host)
+	seg.SetNamespace("remote")
+
+	conn, err := d.connect(ctx, network, address, host)
+	if err != nil {
+		seg.AddError(err)
+		seg.Close()
+		return nil, err
+	}
+	return newConn(ctx, seg, conn), nil
+}
+
+func (d *Dialer) connect(ctx context.Context, network, address, host string) (net.Conn, error) {
+	ctx, seg := xray.BeginSubsegment(ctx, "connect")
+	defer seg.Close()
+
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	segs := nettrace.New(ctx, "net")
+	defer segs.Cancel()
+	conn, err := dialer.DialContext(httptrace.WithClientTrace(ctx, segs.ClientTrace()), network, address)
+	if err != nil {
+		seg.SetFault()
+		return nil, err
+	}
+	if d.TLSConfig == nil {
+		return conn, nil
+	}
+
+	tlsConn, err := handshake(ctx, segs, conn, d.TLSConfig, host)
+	if err != nil {
+		conn.Close()
+		seg.SetFault()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func handshake(ctx context.Context, segs *nettrace.Subsegments, conn net.Conn, config *tls.Config, host string) (net.Conn, error) {
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName = host
+	}
+	tlsConn := tls.Client(conn, config)
+
+	// tls.Conn.HandshakeContext is available from Go 1.17, so emulate it with the deadline.
+	if deadline, ok := ctx.Deadline(); ok {
+		tlsConn.SetDeadline(deadline)
+		defer tlsConn.SetDeadline(time.Time{})
+	}
+	segs.TLSHandshakeStart()
+	err := tlsConn.Handshake()
+	segs.TLSHandshakeDone(tlsConn.ConnectionState(), err)
+	if err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
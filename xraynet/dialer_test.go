@@ -0,0 +1,183 @@
+package xraynet
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray/schema"
+)
+
+func subsegmentNames(seg *schema.Segment) []string {
+	var names []string
+	for _, sub := range seg.Subsegments {
+		names = append(names, sub.Name)
+	}
+	return names
+}
+
+func echoServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l
+}
+
+func TestDialer(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	l := echoServer(t)
+	defer l.Close()
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	func() {
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+
+		d := &Dialer{}
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort("localhost", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Subsegments) != 1 {
+		t.Fatalf("want 1 subsegment, got %d", len(got.Subsegments))
+	}
+	remote := got.Subsegments[0]
+	if remote.Name != "localhost" {
+		t.Errorf("want %q, got %q", "localhost", remote.Name)
+	}
+	if remote.Namespace != "remote" {
+		t.Errorf("want %q, got %q", "remote", remote.Namespace)
+	}
+	meta := remote.Metadata["net"].(map[string]interface{})
+	if meta["bytes_read"] != 5.0 || meta["bytes_written"] != 5.0 {
+		t.Errorf("unexpected bytes: read %v, written %v", meta["bytes_read"], meta["bytes_written"])
+	}
+	if diff := cmp.Diff([]string{"connect"}, subsegmentNames(remote)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	connect := remote.Subsegments[0]
+	names := subsegmentNames(connect)
+	if len(names) < 2 || names[0] != "dns" || names[len(names)-1] != "dial" {
+		t.Errorf("want dns and dial subsegments, got %v", names)
+	}
+}
+
+func TestDialer_DialError(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	// get a free port, and close it.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	func() {
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+
+		d := &Dialer{}
+		if _, err := d.DialContext(ctx, "tcp", addr); err == nil {
+			t.Error("want error, got nil")
+		}
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := got.Subsegments[0]
+	if remote.Name != "127.0.0.1" {
+		t.Errorf("want %q, got %q", "127.0.0.1", remote.Name)
+	}
+	if !remote.Fault {
+		t.Error("want fault, got not")
+	}
+	connect := remote.Subsegments[0]
+	if !connect.Fault {
+		t.Error("want fault, got not")
+	}
+	// the address is an IP address, so DNS lookup is skipped.
+	if diff := cmp.Diff([]string{"dial"}, subsegmentNames(connect)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDialer_TLS(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	func() {
+		ctx, root := xray.BeginSegment(ctx, "test")
+		defer root.Close()
+
+		d := &Dialer{
+			TLSConfig: &tls.Config{
+				RootCAs: ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+			},
+		}
+		conn, err := d.DialContext(ctx, "tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := conn.(*Conn).Conn.(*tls.Conn); !ok {
+			t.Errorf("want *tls.Conn, got %T", conn.(*Conn).Conn)
+		}
+		conn.Close()
+	}()
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	connect := got.Subsegments[0].Subsegments[0]
+	if diff := cmp.Diff([]string{"dial", "tls"}, subsegmentNames(connect)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	meta := connect.Subsegments[1].Metadata["net"].(map[string]interface{})
+	if _, ok := meta["tls"]; !ok {
+		t.Errorf("want tls metadata, got %v", meta)
+	}
+}
@@ -0,0 +1,43 @@
+package xraynet
+
+import (
+	"context"
+	"net"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+// Listener is a traced net.Listener.
+// It begins a new segment for each accepted connection, and the segment is closed when the connection is closed.
+// The accepted connections are *Conn, and their Context method returns the context of the segment.
+type Listener struct {
+	net.Listener
+
+	// Name is the name of the segments.
+	Name string
+
+	// Client is the client of AWS X-Ray. If it is nil, the default client is used.
+	Client *xray.Client
+}
+
+// NewListener wraps l.
+func NewListener(l net.Listener, name string) *Listener {
+	return &Listener{
+		Listener: l,
+		Name:     name,
+	}
+}
+
+// Accept implements net.Listener.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if l.Client != nil {
+		ctx = xray.WithClient(ctx, l.Client)
+	}
+	ctx, seg := xray.BeginSegment(ctx, l.Name)
+	return newConn(ctx, seg, conn), nil
+}
@@ -0,0 +1,73 @@
+package xraynet
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+func TestListener(t *testing.T) {
+	ctx, td := xray.NewTestDaemon(nil)
+	defer td.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl := NewListener(l, "echo")
+	tl.Client = xray.ContextClient(ctx)
+	defer tl.Close()
+
+	go func() {
+		conn, err := tl.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			panic(err)
+		}
+		_, seg := xray.BeginSubsegment(conn.(*Conn).Context(), "command")
+		seg.Close()
+		if _, err := conn.Write(buf); err != nil {
+			panic(err)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := td.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "echo" {
+		t.Errorf("want %q, got %q", "echo", got.Name)
+	}
+	if got.Service == nil {
+		t.Error("want a segment, got a subsegment")
+	}
+	meta := got.Metadata["net"].(map[string]interface{})
+	if meta["bytes_read"] != 5.0 || meta["bytes_written"] != 5.0 {
+		t.Errorf("unexpected bytes: read %v, written %v", meta["bytes_read"], meta["bytes_written"])
+	}
+	if meta["remote_address"] != conn.LocalAddr().String() {
+		t.Errorf("want %q, got %v", conn.LocalAddr().String(), meta["remote_address"])
+	}
+	if len(got.Subsegments) != 1 || got.Subsegments[0].Name != "command" {
+		t.Errorf("want the command subsegment, got %v", got.Subsegments)
+	}
+}